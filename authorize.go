@@ -0,0 +1,44 @@
+package gojsonrpc
+
+import (
+	"context"
+)
+
+// Authorizer checks the identity carried by the context against the requirements declared for a method, returning an
+// error to deny the call; the error is reported like a method's, so an Error or an error registered with MapError or
+// MapErrorType is sent to the caller, and anything else is a generic unauthorized error
+type Authorizer interface {
+	Authorize(c context.Context, method string, requirements []string) error
+}
+
+// AuthorizerFunc adapts a function to an Authorizer
+type AuthorizerFunc func(c context.Context, method string, requirements []string) error
+
+func (fn AuthorizerFunc) Authorize(c context.Context, method string, requirements []string) error {
+	return fn(c, method, requirements)
+}
+
+// WithAuthorizer checks the requirements declared with Require or RequirementDeclarer before each call
+func WithAuthorizer(authorizer Authorizer) Option {
+	return func(h *Handler) {
+		h.authorizer = authorizer
+	}
+}
+
+// authorize checks the method's requirements; methods that declare requirements are denied if there is no authorizer
+func (h *Handler) authorize(c context.Context, req *Request, method *parameterizedMethod) *Error {
+	if len(method.requirements) == 0 {
+		return nil
+	}
+	if h.authorizer == nil {
+		return &Error{Code: CodeUnauthorized, Message: "unauthorized"}
+	}
+	err := h.authorizer.Authorize(c, req.MethodName, method.requirements)
+	if err == nil {
+		return nil
+	}
+	if e := h.errorMappings.find(err); e != nil {
+		return e
+	}
+	return &Error{Code: CodeUnauthorized, Message: "unauthorized"}
+}
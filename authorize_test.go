@@ -0,0 +1,99 @@
+package gojsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testRolesKey struct{}
+
+type TestAdminNamespace struct{}
+
+func (t *TestAdminNamespace) Status() string {
+	return "ok"
+}
+
+func (t *TestAdminNamespace) Reset() bool {
+	return true
+}
+
+func (t *TestAdminNamespace) RPCRequirements(method string) []string {
+	if method == "Reset" {
+		return []string{"admin:write"}
+	}
+	return nil
+}
+
+func TestAuthorize(t *testing.T) {
+	authorizer := AuthorizerFunc(func(c context.Context, method string, requirements []string) error {
+		roles, _ := c.Value(testRolesKey{}).(map[string]bool)
+		for _, r := range requirements {
+			if !roles[r] {
+				return errors.New("missing " + r)
+			}
+		}
+		return nil
+	})
+
+	callCase := func(options []Option, roles map[string]bool, method string, expectedError *Error) func(t *testing.T) {
+		return func(t *testing.T) {
+			h := New(DefaultNext(), options...)
+			must(h.AddNamespace("admin", &TestAdminNamespace{}, Require("admin:read")))
+			must(h.AddMethod("ping", func() string { return "pong" }))
+			must(h.AddMethod("secret", func() string { return "shh" }, Require("secret")))
+			c := context.WithValue(context.Background(), testRolesKey{}, roles)
+			results, err := h.processRequests(c, []Request{{MethodName: method, ID: 1}})
+			assert.NoError(t, err)
+			if assert.Len(t, results, 1) {
+				assert.Equal(t, expectedError, results[0].Error)
+			}
+		}
+	}
+
+	unauthorized := &Error{Code: CodeUnauthorized, Message: "unauthorized"}
+	withAuthorizer := []Option{WithAuthorizer(authorizer)}
+
+	t.Run("NoRequirements", callCase(nil, nil, "ping", nil))
+	t.Run("NoAuthorizer", callCase(nil, map[string]bool{"secret": true}, "secret", unauthorized))
+	t.Run("Method/Allowed", callCase(withAuthorizer, map[string]bool{"secret": true}, "secret", nil))
+	t.Run("Method/Denied", callCase(withAuthorizer, nil, "secret", unauthorized))
+	t.Run("Namespace/Allowed", callCase(withAuthorizer, map[string]bool{"admin:read": true}, "admin.Status", nil))
+	t.Run("Namespace/Denied", callCase(withAuthorizer, nil, "admin.Status", unauthorized))
+	t.Run("Declared/Denied", callCase(withAuthorizer, map[string]bool{"admin:read": true}, "admin.Reset", unauthorized))
+	t.Run("Declared/Allowed", callCase(withAuthorizer, map[string]bool{"admin:read": true, "admin:write": true}, "admin.Reset", nil))
+	// an interceptor answering from a cache must not skip the check
+	cache := WithInterceptors(func(c context.Context, req *Request, next Invoker) (interface{}, *Error) {
+		return "cached", nil
+	})
+	t.Run("Interceptor/Denied", callCase([]Option{cache, WithAuthorizer(authorizer)}, nil, "secret", unauthorized))
+	t.Run("Interceptor/NoAuthorizer", callCase([]Option{cache}, nil, "secret", unauthorized))
+	t.Run("Interceptor/Allowed", callCase([]Option{cache, WithAuthorizer(authorizer)}, map[string]bool{"secret": true}, "secret", nil))
+
+	// an interceptor that switches the request to another method is checked against that method
+	rename := WithInterceptors(func(c context.Context, req *Request, next Invoker) (interface{}, *Error) {
+		req.MethodName = "secret"
+		return next(c, req)
+	})
+	t.Run("Interceptor/Rename", callCase([]Option{rename, WithAuthorizer(authorizer)}, nil, "ping", unauthorized))
+
+	t.Run("ValueError", callCase([]Option{WithAuthorizer(AuthorizerFunc(func(c context.Context, method string, requirements []string) error {
+		return Error{Code: 1004, Message: "expired"}
+	}))}, nil, "secret", &Error{Code: 1004, Message: "expired"}))
+
+	t.Run("MappedError", func(t *testing.T) {
+		h := New(DefaultNext(), WithAuthorizer(AuthorizerFunc(func(c context.Context, method string, requirements []string) error {
+			return fmt.Errorf("token: %w", errTestNotFound)
+		})))
+		h.MapError(errTestNotFound, 1005, nil)
+		must(h.AddMethod("secret", func() string { return "shh" }, Require("secret")))
+		results, _ := h.processRequests(context.Background(), []Request{{MethodName: "secret", ID: 1}})
+		assert.Equal(t, &Error{Code: 1005, Message: "token: not found"}, results[0].Error)
+	})
+
+	t.Run("CustomError", callCase([]Option{WithAuthorizer(AuthorizerFunc(func(c context.Context, method string, requirements []string) error {
+		return &Error{Code: 1003, Message: "current user is not authorized"}
+	}))}, nil, "secret", &Error{Code: 1003, Message: "current user is not authorized"}))
+}
@@ -0,0 +1,108 @@
+package gojsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// callRequest is a request sent by the server to the client
+type callRequest struct {
+	Version string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	ID      string      `json:"id"`
+}
+
+// callResponse is a response from the client; it has no method, and either a result or an error
+type callResponse struct {
+	Method *string         `json:"method"`
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+func (r callResponse) isResponse() bool {
+	return r.Method == nil && (r.Result != nil || r.Error != nil)
+}
+
+// Call calls method on the client and waits for its response, which is decoded into result unless result is nil; an
+// error response from the client is returned as an *Error
+func (ws *Conn) Call(c context.Context, method string, params interface{}, result interface{}) error {
+	if ws == nil {
+		return ErrNotWebsocket
+	}
+	response := make(chan callResponse, 1)
+
+	ws.pendingMu.Lock()
+	if ws.c.Err() != nil {
+		ws.pendingMu.Unlock()
+		return ErrConnClosed
+	}
+	ws.lastID++
+	id := fmt.Sprintf("server-%d", ws.lastID)
+	ws.pending[id] = response
+	ws.pendingMu.Unlock()
+	defer func() {
+		ws.pendingMu.Lock()
+		delete(ws.pending, id)
+		ws.pendingMu.Unlock()
+	}()
+
+	if err := ws.send(callRequest{requestVersion, method, params, id}); err != nil {
+		return err
+	}
+
+	select {
+	case res := <-response:
+		if res.Error != nil {
+			return res.Error
+		}
+		if result != nil {
+			return json.Unmarshal(res.Result, result)
+		}
+		return nil
+	case <-c.Done():
+		return c.Err()
+	case <-ws.c.Done():
+		return ErrConnClosed
+	}
+}
+
+// routeResponses passes the responses in a frame to the calls waiting for them, returning false if the frame is not a
+// response or a batch of responses
+func (ws *Conn) routeResponses(p []byte) bool {
+	var responses []callResponse
+	if trimmed := bytes.TrimSpace(p); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &responses); err != nil || len(responses) == 0 {
+			return false
+		}
+	} else {
+		var response callResponse
+		if err := json.Unmarshal(trimmed, &response); err != nil {
+			return false
+		}
+		responses = []callResponse{response}
+	}
+	for _, response := range responses {
+		if !response.isResponse() {
+			return false
+		}
+	}
+
+	ws.pendingMu.Lock()
+	defer ws.pendingMu.Unlock()
+	for _, response := range responses {
+		var id string
+		json.Unmarshal(response.ID, &id)
+		if pending, ok := ws.pending[id]; ok {
+			delete(ws.pending, id)
+			pending <- response
+		} else {
+			log.Printf("dropped response to unknown call %s", response.ID)
+		}
+	}
+	return true
+}
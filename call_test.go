@@ -0,0 +1,94 @@
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type TestPromptNamespace struct{}
+
+func (t *TestPromptNamespace) Delete(c context.Context, ws *Conn, name string) (string, error) {
+	var confirmed bool
+	if err := ws.Call(c, "ui.confirm", []string{"delete " + name + "?"}, &confirmed); err != nil {
+		return "", err
+	}
+	if !confirmed {
+		return "kept", nil
+	}
+	return "deleted", nil
+}
+
+func (t *TestPromptNamespace) Wait(ws *Conn) error {
+	c, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	return ws.Call(c, "ui.never", nil, nil)
+}
+
+func TestConnCall(t *testing.T) {
+	h := New(DefaultNext())
+	must(h.AddNamespace("files", &TestPromptNamespace{}))
+
+	// answer sends a call to the server and replies to the call the server makes back with reply
+	answer := func(t *testing.T, conn *websocket.Conn, reply func(id string) string) Result {
+		must(conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"files.Delete","params":["a.txt"],"id":1}`)))
+		var call struct {
+			Version string `json:"jsonrpc"`
+			Method  string
+			Params  []string
+			ID      string
+		}
+		must(json.Unmarshal([]byte(readMessage(t, conn)), &call))
+		assert.Equal(t, "2.0", call.Version)
+		assert.Equal(t, "ui.confirm", call.Method)
+		assert.Equal(t, []string{"delete a.txt?"}, call.Params)
+		must(conn.WriteMessage(websocket.TextMessage, []byte(reply(call.ID))))
+
+		var result Result
+		must(json.Unmarshal([]byte(readMessage(t, conn)), &result))
+		return result
+	}
+
+	conn, done := dial(t, h)
+	defer done()
+
+	t.Run("Result", func(t *testing.T) {
+		result := answer(t, conn, func(id string) string {
+			return `{"jsonrpc":"2.0","id":"` + id + `","result":true}`
+		})
+		assert.Equal(t, "deleted", result.Result)
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		result := answer(t, conn, func(id string) string {
+			return `[{"jsonrpc":"2.0","id":"` + id + `","result":false}]`
+		})
+		assert.Equal(t, "kept", result.Result)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		result := answer(t, conn, func(id string) string {
+			return `{"jsonrpc":"2.0","id":"` + id + `","error":{"code":-32601,"message":"no ui"}}`
+		})
+		assert.Equal(t, &Error{Code: -32601, Message: "no ui"}, result.Error)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		must(conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"files.Wait","id":2}`)))
+		readMessage(t, conn)
+		var result Result
+		must(json.Unmarshal([]byte(readMessage(t, conn)), &result))
+		assert.Equal(t, float64(2), result.ID)
+		assert.NotNil(t, result.Error)
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		w := serve(h, `{"jsonrpc":"2.0","method":"files.Delete","params":["a.txt"],"id":1}`)
+		var result Result
+		must(json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, ErrNotWebsocket.Error(), result.Error.Message)
+	})
+}
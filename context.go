@@ -0,0 +1,59 @@
+package gojsonrpc
+
+import (
+	"context"
+	"net/http"
+)
+
+// CallInfo describes the call a method is serving
+type CallInfo struct {
+	ID        interface{}
+	Method    string
+	Batch     bool
+	Transport Transport
+	// Request is the HTTP request, or for WebSocket the upgrade request; it carries the headers and remote address
+	Request *http.Request
+}
+
+type messageInfoKey struct{}
+type callInfoKey struct{}
+
+// messageInfo describes the HTTP body or WebSocket message the calls came in
+type messageInfo struct {
+	transport Transport
+	request   *http.Request
+	batch     bool
+}
+
+func withMessageInfo(c context.Context, transport Transport, r *http.Request, batch bool) context.Context {
+	return context.WithValue(c, messageInfoKey{}, messageInfo{transport, r, batch})
+}
+
+// withCallInfo adds the metadata for a single call to the context
+func withCallInfo(c context.Context, req *Request) context.Context {
+	message, _ := c.Value(messageInfoKey{}).(messageInfo)
+	return context.WithValue(c, callInfoKey{}, CallInfo{
+		ID:        req.ID,
+		Method:    req.MethodName,
+		Batch:     message.batch,
+		Transport: message.transport,
+		Request:   message.request,
+	})
+}
+
+// CallInfoFromContext returns the metadata of the call being served
+func CallInfoFromContext(c context.Context) (CallInfo, bool) {
+	info, ok := c.Value(callInfoKey{}).(CallInfo)
+	return info, ok
+}
+
+// HTTPRequestFromContext returns the HTTP request the call came in, or for WebSocket the upgrade request
+func HTTPRequestFromContext(c context.Context) (*http.Request, bool) {
+	if info, ok := CallInfoFromContext(c); ok && info.Request != nil {
+		return info.Request, true
+	}
+	if message, ok := c.Value(messageInfoKey{}).(messageInfo); ok && message.request != nil {
+		return message.request, true
+	}
+	return nil, false
+}
@@ -0,0 +1,65 @@
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCallInfo(t *testing.T) {
+	type seen struct {
+		info CallInfo
+		ok   bool
+	}
+	var calls []seen
+	h := New(DefaultNext(), WithSequentialBatches())
+	must(h.AddMethod("audit", func(c context.Context) string {
+		info, ok := CallInfoFromContext(c)
+		calls = append(calls, seen{info, ok})
+		r, _ := HTTPRequestFromContext(c)
+		return r.RemoteAddr + " " + r.Header.Get("User-Agent")
+	}))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("POST", "/", strings.NewReader(body))
+		must(err)
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("User-Agent", "test-agent")
+		r.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := post(`{"jsonrpc":"2.0","method":"audit","id":"a"}`)
+	var result Result
+	must(json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "10.0.0.1:1234 test-agent", result.Result)
+
+	post(`[{"jsonrpc":"2.0","method":"audit","id":1},{"jsonrpc":"2.0","method":"audit"}]`)
+
+	if assert.Len(t, calls, 3) {
+		for _, call := range calls {
+			assert.True(t, call.ok)
+			assert.Equal(t, "audit", call.info.Method)
+			assert.Equal(t, TransportHTTP, call.info.Transport)
+			assert.NotNil(t, call.info.Request)
+		}
+		assert.Equal(t, "a", calls[0].info.ID)
+		assert.False(t, calls[0].info.Batch)
+		assert.Equal(t, float64(1), calls[1].info.ID)
+		assert.True(t, calls[1].info.Batch)
+		assert.Nil(t, calls[2].info.ID)
+	}
+}
+
+func TestCallInfoMissing(t *testing.T) {
+	_, ok := CallInfoFromContext(context.Background())
+	assert.False(t, ok)
+	_, ok = HTTPRequestFromContext(context.Background())
+	assert.False(t, ok)
+}
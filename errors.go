@@ -0,0 +1,100 @@
+package gojsonrpc
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// errorMapping reports a matching Go error with a JSON-RPC code and optional data
+type errorMapping struct {
+	sentinel error        // matched with errors.Is
+	target   reflect.Type // matched with errors.As
+	code     int
+	data     func(err error) interface{}
+}
+
+// errorRegistry converts errors returned by methods into JSON-RPC errors
+type errorRegistry struct {
+	mu       sync.RWMutex
+	mappings []errorMapping
+}
+
+func newErrorRegistry() *errorRegistry {
+	return &errorRegistry{}
+}
+
+func (r *errorRegistry) add(m errorMapping) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mappings = append(r.mappings, m)
+}
+
+// marshal finds the JSON-RPC error for err, and anything without one is a generic server error
+func (r *errorRegistry) marshal(err error) *Error {
+	if e := r.find(err); e != nil {
+		return e
+	}
+	return &Error{
+		Code:    CodeServerError,
+		Message: err.Error(),
+	}
+}
+
+// find looks for the JSON-RPC error for err; an *Error or Error anywhere in the chain is used as is, then registered
+// mappings are checked in the order they were added. It returns nil if nothing matches
+func (r *errorRegistry) find(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	var rpcErrValue Error
+	if errors.As(err, &rpcErrValue) {
+		return &rpcErrValue
+	}
+
+	if r != nil {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		for _, m := range r.mappings {
+			if matched, ok := m.match(err); ok {
+				e := &Error{Code: m.code, Message: err.Error()}
+				if m.data != nil {
+					e.Data = m.data(matched)
+				}
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// match checks err against the mapping, returning the error in the chain that matched
+func (m errorMapping) match(err error) (error, bool) {
+	if m.sentinel != nil {
+		return m.sentinel, errors.Is(err, m.sentinel)
+	}
+	target := reflect.New(m.target)
+	if errors.As(err, target.Interface()) {
+		return target.Elem().Interface().(error), true
+	}
+	return nil, false
+}
+
+// MapError reports any error matching sentinel (using errors.Is) with code; data is optional and builds the data member
+// from the sentinel. It panics if sentinel is nil
+func (h *Handler) MapError(sentinel error, code int, data func(err error) interface{}) {
+	if sentinel == nil {
+		panic("MapError expects a sentinel error; got nil")
+	}
+	h.errorMappings.add(errorMapping{sentinel: sentinel, code: code, data: data})
+}
+
+// MapErrorType reports any error of the same type as example (using errors.As) with code; data is optional and builds
+// the data member from the matched error. It panics if example is nil
+func (h *Handler) MapErrorType(example error, code int, data func(err error) interface{}) {
+	if example == nil {
+		panic("MapErrorType expects an example error; got nil")
+	}
+	h.errorMappings.add(errorMapping{target: reflect.TypeOf(example), code: code, data: data})
+}
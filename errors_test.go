@@ -0,0 +1,47 @@
+package gojsonrpc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+var errTestNotFound = errors.New("not found")
+
+type TestRetryError struct {
+	After int
+}
+
+func (t *TestRetryError) Error() string {
+	return fmt.Sprintf("retry after %d", t.After)
+}
+
+func TestErrorRegistry(t *testing.T) {
+	r := newErrorRegistry()
+	r.add(errorMapping{sentinel: errTestNotFound, code: 404})
+	r.add(errorMapping{target: reflect.TypeOf(&TestRetryError{}), code: 429, data: func(err error) interface{} {
+		return map[string]int{"retryAfter": err.(*TestRetryError).After}
+	}})
+
+	marshalCase := func(err error, expected *Error) func(t *testing.T) {
+		return func(t *testing.T) {
+			assert.Equal(t, expected, r.marshal(err))
+		}
+	}
+
+	t.Run("Sentinel", marshalCase(errTestNotFound, &Error{Code: 404, Message: "not found"}))
+	t.Run("Sentinel/Wrapped", marshalCase(fmt.Errorf("user 5: %w", errTestNotFound), &Error{Code: 404, Message: "user 5: not found"}))
+	t.Run("Type", marshalCase(&TestRetryError{5}, &Error{Code: 429, Message: "retry after 5", Data: map[string]int{"retryAfter": 5}}))
+	t.Run("Type/Wrapped", marshalCase(fmt.Errorf("busy: %w", &TestRetryError{3}), &Error{Code: 429, Message: "busy: retry after 3", Data: map[string]int{"retryAfter": 3}}))
+	t.Run("Error/Wrapped", marshalCase(fmt.Errorf("denied: %w", &Error{Code: 1003, Message: "no"}), &Error{Code: 1003, Message: "no"}))
+	t.Run("Unknown", marshalCase(errors.New("what happened"), &Error{Code: CodeServerError, Message: "what happened"}))
+}
+
+func TestMapErrorNil(t *testing.T) {
+	h := New(DefaultNext())
+	assert.Panics(t, func() { h.MapError(nil, 404, nil) })
+	assert.Panics(t, func() { h.MapErrorType(nil, 429, nil) })
+	assert.Equal(t, &Error{Code: CodeServerError, Message: "what happened"}, h.errorMappings.marshal(errors.New("what happened")))
+}
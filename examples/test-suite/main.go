@@ -17,8 +17,9 @@ func main() {
 
 	t := &TestNamespace{}
 
-	h := gojsonrpc.New(gojsonrpc.DefaultNext())
+	h := gojsonrpc.New(gojsonrpc.DefaultNext(), gojsonrpc.WithAuthorizer(gojsonrpc.AuthorizerFunc(UserAuthorizer)))
 	must(h.AddNamespace("test", t))
+	must(h.AddMethod("ping", func() string { return "pong" }))
 	log.Fatal(http.ListenAndServe(":8080", AuthMiddleware(h)))
 }
 
@@ -33,12 +34,22 @@ func (t *TestNamespace) Sum(a int, b int, nums ...int) int {
 	return total
 }
 
-func (t *TestNamespace) SecureSum(c context.Context, a int, b int, nums ...int) (int, error) {
-	if !HasUser(c) {
-		return 0, &gojsonrpc.Error{ Code: 1003, Message: "current user is not authorized" }
+func (t *TestNamespace) SecureSum(a int, b int, nums ...int) int {
+	return t.Sum(a, b, nums...)
+}
+
+func (t *TestNamespace) RPCRequirements(method string) []string {
+	if method == "SecureSum" {
+		return []string{"user"}
 	}
+	return nil
+}
 
-	return t.Sum(a, b, nums...), nil
+func UserAuthorizer(c context.Context, method string, requirements []string) error {
+	if !HasUser(c) {
+		return &gojsonrpc.Error{ Code: 1003, Message: "current user is not authorized" }
+	}
+	return nil
 }
 
 func AuthMiddleware(next http.Handler) http.Handler {
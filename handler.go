@@ -5,17 +5,28 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/gorilla/websocket"
 	"log"
 	"net/http"
 	"reflect"
-	"github.com/gorilla/websocket"
+	"time"
 )
 
+// Request is a single JSON-RPC call; params are either positional (Parameters) or by-name (NamedParameters)
+// A Notification has no id and never gets a response
 type Request struct {
-	Version    string            `json:"jsonrpc"`
-	MethodName string            `json:"method"`
-	Parameters []json.RawMessage `json:"params"`
-	ID         interface{}       `json:"id"`
+	Version         string
+	MethodName      string
+	Parameters      []json.RawMessage
+	NamedParameters map[string]json.RawMessage
+	ID              interface{}
+	Notification    bool
+
+	// invalid is set when the request could not be understood; it is answered with this error
+	invalid *Error
+	// lenient is set on invalid requests that were called as usual before WithSpecErrors existed, which they still are
+	// without it
+	lenient bool
 }
 
 type Result struct {
@@ -26,29 +37,94 @@ type Result struct {
 }
 
 type Error struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
 }
 
+// error codes defined by the JSON-RPC 2.0 specification
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeServerError    = -32000
+)
+
+// Transport is how a request reached the handler
+type Transport string
+
+const (
+	TransportHTTP      Transport = "http"
+	TransportWebsocket Transport = "websocket"
+)
+
+// error codes used by this package, in the range reserved for server errors
+const (
+	CodeUnauthorized = -32001
+	CodeTimeout      = -32002
+	CodeServerBusy   = -32003
+)
+
 func (e Error) Error() string {
 	return e.Message
 }
 
-func New(next HandlerNext) *Handler {
-	return &Handler{
-		next,
-		make(map[string]*parameterizedMethod),
-		websocket.Upgrader{
+func New(next HandlerNext, options ...Option) *Handler {
+	h := &Handler{
+		next:          next,
+		methods:       newMethodRegistry(),
+		errorMappings: newErrorRegistry(),
+		metrics:       noMetrics{},
+		tracer:        noTracer{},
+		injectors:     make(injectorRegistry),
+		hub:           newHub(),
+		queueSize:     defaultQueueSize,
+		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 		},
 	}
+	for _, option := range options {
+		option(h)
+	}
+	h.addBuiltinMethods()
+	return h
 }
 
 type Handler struct {
 	next          HandlerNext
-	cachedMethods map[string]*parameterizedMethod
-	upgrader websocket.Upgrader
+	methods       *methodRegistry
+	builtins      map[string]*parameterizedMethod
+	errorMappings *errorRegistry
+	upgrader      websocket.Upgrader
+	specErrors    bool
+	panicHandler  PanicHandler
+	interceptors  []Interceptor
+	authorizer    Authorizer
+	timeout       time.Duration
+	metrics       MetricsCollector
+	tracer        Tracer
+	injectors     injectorRegistry
+	errorStatus   map[int]int
+
+	// batch execution and limits
+	batchParallelism int
+	maxBatchSize     int
+	inFlight         chan struct{}
+	maxBodySize      int64
+
+	// WebSocket connections
+	hub              *hub
+	queueSize        int
+	maxMessageSize   int64
+	pingInterval     time.Duration
+	idleTimeout      time.Duration
+	compressionLevel int
+
+	// defaults for every namespace
+	namespaceOptions []MethodOption
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -57,7 +133,51 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	requests, err := parseRPCRequests(r)
+	h.metrics.AddInFlight(TransportHTTP, 1)
+	defer h.metrics.AddInFlight(TransportHTTP, -1)
+
+	c, span := h.tracer.StartSpan(withTraceParent(r.Context(), r), SpanHTTP)
+	defer span.Finish()
+	r = r.WithContext(c)
+
+	if c, cancel, ok := withClientTimeout(r); ok {
+		defer cancel()
+		r = r.WithContext(c)
+	}
+
+	if h.maxBodySize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxBodySize)
+	}
+
+	requests, batch, err := parseRPCRequests(r, h.maxBatchSize)
+	if err == nil && !h.specErrors {
+		for i := range requests {
+			if requests[i].invalid == nil {
+				continue
+			}
+			if requests[i].lenient {
+				requests[i].invalid = nil
+				continue
+			}
+			err = errorInvalidJson{requests[i].invalid, "request"}
+			break
+		}
+	}
+	if _, ok := err.(errorInvalidJson); ok && h.specErrors {
+		requests, batch, err = []Request{{invalid: &Error{Code: CodeParseError, Message: "parse error"}}}, false, nil
+	}
+	if _, ok := err.(errorEmptyBatch); ok {
+		if !h.specErrors {
+			// answered as it was before spec errors existed
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("null"))
+			return
+		}
+		requests, batch, err = []Request{emptyBatch()}, false, nil
+	}
+	if tooLarge, ok := err.(errorBatchTooLarge); ok {
+		requests, batch, err = []Request{batchTooLarge(tooLarge.limit)}, false, nil
+	}
 	if err != nil {
 		c := context.WithValue(r.Context(), "error", err)
 		r = r.WithContext(c)
@@ -66,6 +186,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			h.next.BadContentType.ServeHTTP(w, r)
 		case errorInvalidJson:
 			h.next.InvalidJSON.ServeHTTP(w, r)
+		case errorRequestTooLarge:
+			h.next.requestTooLarge().ServeHTTP(w, r)
 		default:
 			h.next.InternalServerError.ServeHTTP(w, r)
 		}
@@ -73,97 +195,154 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// for each request
-	// - create a new goroutine to solve it
-	// - wait until resolved
-	results, err := h.processRequests(r.Context(), requests)
+	// - solve it, in parallel up to the batch parallelism
+	// - wait until resolved, keeping request order
+	response := newResponse()
+	c = withMessageInfo(r.Context(), TransportHTTP, r, batch)
+	c = withResponse(c, response)
+	results, err := h.processRequests(c, requests)
 	if err != nil {
 		panic(err)
 	}
 
-	var b []byte
-	// serialize result; if one value, then just respond with that; otherwise respond with array
-	if len(requests) == 1 {
-		b, err = json.Marshal(results[0])
-	} else {
-		b, err = json.Marshal(results)
+	// notifications get no response; if nothing is left then there is no body to send
+	if len(results) == 0 {
+		h.writeHeader(w, response, batch, results, http.StatusNoContent)
+		return
 	}
 
-	if err != nil {
-		// something terrible happened
-		panic(err)
-	}
+	// serialize result; if one value, then just respond with that; otherwise respond with array
+	b := marshalResults(results, batch)
 
 	w.Header().Set("Content-Type", "application/json")
+	h.writeHeader(w, response, batch, results, http.StatusOK)
 	w.Write(b)
 }
 
-func (h *Handler) ServeWebsocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := h.upgrader.Upgrade(w, r, nil)
-	defer conn.Close()
-	if err != nil {
-		log.Println(err)
-		return
-	}
-	writer := make(chan interface{})
-	go func() {
-		for {
-			msg, ok := <- writer
-			if !ok {
-				return
-			}
-			if err = conn.WriteJSON(msg); err != nil {
-				panic(err)
-			}
-		}
-	}()
-	for {
-		_, p, err := conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
-				log.Printf("error: %v, user-agent: %v", err, r.Header.Get("User-Agent"))
-			}
-			close(writer)
-			return
-		}
-		go func(p []byte) {
-			var req Request
-			if err := json.Unmarshal(p, &req); err != nil {
-				panic(err)
-			}
-			results, err := h.processRequests(r.Context(), []Request{req})
-			if err != nil {
-				panic(err)
-			}
-			for _, res := range results {
-				writer <- res
-			}
-		}(p)
-	}
-}
-
-func (h *Handler) AddNamespace(name string, object interface{}) error {
+// AddNamespace registers every exported method of object as "name.Method"; adding a namespace that already exists
+// replaces all of its methods at once
+func (h *Handler) AddNamespace(name string, object interface{}, options ...MethodOption) error {
 	v := reflect.ValueOf(object)
 	if !v.IsValid() {
 		return errors.New(fmt.Sprintf("Invalid value for namespace: %v", v))
 	}
-	// iterate over every method in the namespace
+	config := newMethodConfig(h.namespaceOptions, options)
 	t := v.Type()
+	if config.iface != nil && !t.Implements(config.iface) {
+		return fmt.Errorf("Namespace %s does not implement %v", name, config.iface)
+	}
+	if excluder, ok := object.(MethodExcluder); ok {
+		for _, m := range excluder.RPCExcludedMethods() {
+			config.exclude[m] = true
+		}
+	}
+	namer, hasNames := object.(ParameterNamer)
+	declarer, hasRequirements := object.(RequirementDeclarer)
+	methods := make(map[string]*parameterizedMethod)
+	// iterate over every method in the namespace
 	for i := 0; i < t.NumMethod(); i++ {
 		m := t.Method(i)
-		methodname := fmt.Sprintf("%s.%s", name, m.Name)
-		parameterized, err := newParameterizedMethod(v.Method(i))
+		if !config.exposes(m.Name) {
+			continue
+		}
+		methodname := name + config.separator + config.methodName(m.Name)
+		parameterized, err := h.newMethod(v.Method(i))
 		if err != nil {
 			return err
 		}
-		h.cachedMethods[methodname] = parameterized
+		if hasNames {
+			if names := namer.RPCParameterNames(m.Name); names != nil {
+				if err := parameterized.setParameterNames(names); err != nil {
+					return fmt.Errorf("%s: %v", methodname, err)
+				}
+			}
+		}
+		requirements := config.requirements
+		if hasRequirements {
+			requirements = append(append([]string{}, requirements...), declarer.RPCRequirements(m.Name)...)
+		}
+		parameterized.requirements = requirements
+		parameterized.timeout = config.timeout
+		methods[methodname] = parameterized
 	}
+	h.methods.setNamespace(name, methods)
 	return nil
 }
 
+// AddMethod registers a standalone function or closure under name, replacing any method already registered with that name
+func (h *Handler) AddMethod(name string, fn interface{}, options ...MethodOption) error {
+	v := reflect.ValueOf(fn)
+	if !v.IsValid() || v.Kind() != reflect.Func {
+		return fmt.Errorf("Invalid function for method %s: %v", name, v)
+	}
+	config := newMethodConfig(nil, options)
+	parameterized, err := h.newMethod(v)
+	if err != nil {
+		return err
+	}
+	if config.parameterNames != nil {
+		if err := parameterized.setParameterNames(config.parameterNames); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	parameterized.requirements = config.requirements
+	parameterized.timeout = config.timeout
+	h.methods.set(name, parameterized)
+	return nil
+}
+
+// RemoveMethod removes the method registered under name, returning false if there was none
+func (h *Handler) RemoveMethod(name string) bool {
+	return h.methods.remove(name)
+}
+
+// newMethod analyses a method or function for registration on this handler
+func (h *Handler) newMethod(m reflect.Value) (*parameterizedMethod, error) {
+	parameterized, err := newParameterizedMethod(m, h.injectors)
+	if err != nil {
+		return nil, err
+	}
+	parameterized.errorMappings = h.errorMappings
+	return parameterized, nil
+}
+
+// ParameterNamer can be implemented by a namespace to declare the parameter names of its methods, allowing them to be
+// called with by-name params; context parameters are not named
+type ParameterNamer interface {
+	RPCParameterNames(method string) []string
+}
+
+// MethodExcluder can be implemented by a namespace to keep some of its exported methods from being exposed
+type MethodExcluder interface {
+	RPCExcludedMethods() []string
+}
+
+// RequirementDeclarer can be implemented by a namespace to declare the roles or scopes each method requires, in
+// addition to those given with Require when the namespace is added
+type RequirementDeclarer interface {
+	RPCRequirements(method string) []string
+}
+
+// reservedMethodNames are methods used to configure a namespace, which are never exposed as RPC methods
+var reservedMethodNames = map[string]bool{
+	"RPCParameterNames":  true,
+	"RPCExcludedMethods": true,
+	"RPCRequirements":    true,
+}
+
 type HandlerNext struct {
 	BadContentType      http.Handler
 	InvalidJSON         http.Handler
 	InternalServerError http.Handler
+	RequestTooLarge     http.Handler
+}
+
+// requestTooLarge falls back to the default for a HandlerNext built before RequestTooLarge existed
+func (n HandlerNext) requestTooLarge() http.Handler {
+	if n.RequestTooLarge != nil {
+		return n.RequestTooLarge
+	}
+	return DefaultNext().RequestTooLarge
 }
 
 func DefaultNext() HandlerNext {
@@ -178,5 +357,8 @@ func DefaultNext() HandlerNext {
 			log.Print(r.Context().Value("error"))
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		}),
+		RequestTooLarge: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		}),
 	}
 }
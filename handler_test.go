@@ -0,0 +1,219 @@
+package gojsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type TestHandlerNamespace struct {
+	calls int32
+}
+
+func (t *TestHandlerNamespace) Add(a int, b int) int {
+	atomic.AddInt32(&t.calls, 1)
+	return a + b
+}
+
+func serve(h http.Handler, body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "/", strings.NewReader(body))
+	must(err)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandlerNotifications(t *testing.T) {
+	newHandler := func() (*Handler, *TestHandlerNamespace) {
+		n := &TestHandlerNamespace{}
+		h := New(DefaultNext())
+		must(h.AddNamespace("test", n))
+		return h, n
+	}
+
+	t.Run("Single", func(t *testing.T) {
+		h, n := newHandler()
+		w := serve(h, `{"jsonrpc":"2.0","method":"test.Add","params":[1,2]}`)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, int32(1), n.calls)
+	})
+
+	t.Run("NullID", func(t *testing.T) {
+		h, _ := newHandler()
+		w := serve(h, `{"jsonrpc":"2.0","method":"test.Add","params":[1,2],"id":null}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result Result
+		must(json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Nil(t, result.ID)
+		assert.Equal(t, float64(3), result.Result)
+	})
+
+	t.Run("Batch/Mixed", func(t *testing.T) {
+		h, n := newHandler()
+		w := serve(h, `[{"jsonrpc":"2.0","method":"test.Add","params":[1,2]},{"jsonrpc":"2.0","method":"test.Add","params":[3,4],"id":7}]`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var results []Result
+		must(json.Unmarshal(w.Body.Bytes(), &results))
+		if assert.Len(t, results, 1) {
+			assert.Equal(t, float64(7), results[0].ID)
+			assert.Equal(t, float64(7), results[0].Result)
+		}
+		assert.Equal(t, int32(2), n.calls)
+	})
+
+	t.Run("Batch/AllNotifications", func(t *testing.T) {
+		h, n := newHandler()
+		w := serve(h, `[{"jsonrpc":"2.0","method":"test.Add","params":[1,2]},{"jsonrpc":"2.0","method":"test.Missing"}]`)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, int32(1), n.calls)
+	})
+}
+
+func TestHandlerSpecErrors(t *testing.T) {
+	newHandler := func(options ...Option) *Handler {
+		h := New(DefaultNext(), options...)
+		must(h.AddNamespace("test", &TestHandlerNamespace{}))
+		return h
+	}
+
+	single := func(body string, expectedCode int) func(t *testing.T) {
+		return func(t *testing.T) {
+			w := serve(newHandler(WithSpecErrors()), body)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var result Result
+			must(json.Unmarshal(w.Body.Bytes(), &result))
+			assert.Nil(t, result.ID)
+			if assert.NotNil(t, result.Error) {
+				assert.Equal(t, expectedCode, result.Error.Code)
+			}
+		}
+	}
+
+	t.Run("ParseError", single(`{"jsonrpc":"2.0","method":"test.Add"`, CodeParseError))
+	t.Run("InvalidRequest", single(`{"jsonrpc":"2.0","method":1,"params":"bar"}`, CodeInvalidRequest))
+	t.Run("MissingMethod", single(`{"jsonrpc":"2.0","id":1}`, CodeInvalidRequest))
+	t.Run("EmptyBatch", single(`[]`, CodeInvalidRequest))
+
+	t.Run("Batch", func(t *testing.T) {
+		w := serve(newHandler(WithSpecErrors()), `[1,{"jsonrpc":"2.0","method":"test.Add","params":[1,2],"id":1}]`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var results []Result
+		must(json.Unmarshal(w.Body.Bytes(), &results))
+		if assert.Len(t, results, 2) {
+			for _, result := range results {
+				if result.ID == nil {
+					assert.Equal(t, CodeInvalidRequest, result.Error.Code)
+				} else {
+					assert.Equal(t, float64(3), result.Result)
+				}
+			}
+		}
+	})
+
+	t.Run("BatchOfOne", func(t *testing.T) {
+		w := serve(newHandler(WithSpecErrors()), `[{"jsonrpc":"2.0","method":"test.Add","params":[1,2],"id":1}]`)
+		var results []Result
+		must(json.Unmarshal(w.Body.Bytes(), &results))
+		assert.Len(t, results, 1)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		w := serve(newHandler(), `[1,{"jsonrpc":"2.0","method":"test.Add","params":[1,2],"id":1}]`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Disabled/MissingMethod", func(t *testing.T) {
+		// a request without a method was always answered with method not found
+		w := serve(newHandler(), `{"jsonrpc":"2.0","id":1}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"jsonrpc":"2.0-x","id":1,"error":{"code":-32601,"message":"method not found on server"}}`, w.Body.String())
+	})
+
+	t.Run("Disabled/EmptyBatch", func(t *testing.T) {
+		// an empty batch was always answered with null
+		w := serve(newHandler(), `[]`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "null", w.Body.String())
+	})
+}
+
+type TestErrorNamespace struct{}
+
+func (t *TestErrorNamespace) Find(id int) (int, error) {
+	return 0, fmt.Errorf("user %d: %w", id, errTestNotFound)
+}
+
+func TestHandlerMapError(t *testing.T) {
+	h := New(DefaultNext())
+	h.MapError(errTestNotFound, 404, func(err error) interface{} { return "missing" })
+	must(h.AddNamespace("test", &TestErrorNamespace{}))
+
+	w := serve(h, `{"jsonrpc":"2.0","method":"test.Find","params":[5],"id":1}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0-x","id":1,"error":{"code":404,"message":"user 5: not found","data":"missing"}}`, w.Body.String())
+}
+
+func TestHandlerAddMethod(t *testing.T) {
+	h := New(DefaultNext())
+	must(h.AddMethod("ping", func() string { return "pong" }))
+	must(h.AddMethod("sub", func(a int, b int) int { return a - b }, ParameterNames("a", "b")))
+
+	t.Run("Call", func(t *testing.T) {
+		w := serve(h, `{"jsonrpc":"2.0","method":"ping","id":1}`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0-x","id":1,"result":"pong"}`, w.Body.String())
+	})
+
+	t.Run("Named", func(t *testing.T) {
+		w := serve(h, `{"jsonrpc":"2.0","method":"sub","params":{"b":1,"a":3},"id":1}`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0-x","id":1,"result":2}`, w.Body.String())
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		must(h.AddMethod("ping", func() string { return "PONG" }))
+		w := serve(h, `{"jsonrpc":"2.0","method":"ping","id":1}`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0-x","id":1,"result":"PONG"}`, w.Body.String())
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		assert.True(t, h.RemoveMethod("ping"))
+		assert.False(t, h.RemoveMethod("ping"))
+		w := serve(h, `{"jsonrpc":"2.0","method":"ping","id":1}`)
+		var result Result
+		must(json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, CodeMethodNotFound, result.Error.Code)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Error(t, h.AddMethod("bad", 5))
+		assert.Error(t, h.AddMethod("bad", func(a int) int { return a }, ParameterNames("a", "b")))
+	})
+}
+
+type TestErrorMarshaler struct{}
+
+func (TestErrorMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("cannot marshal")
+}
+
+func TestHandlerMarshalPanic(t *testing.T) {
+	h := New(DefaultNext())
+	must(h.AddMethod("bad", func() TestPanicMarshaler { return TestPanicMarshaler{} }))
+	must(h.AddMethod("broken", func() TestErrorMarshaler { return TestErrorMarshaler{} }))
+	must(h.AddMethod("ping", func() string { return "pong" }))
+
+	w := serve(h, `{"jsonrpc":"2.0","method":"bad","id":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"jsonrpc":"2.0-x","id":1,"error":{"code":-32603,"message":"internal error"}}`, w.Body.String())
+
+	// in a batch only the calls that cannot be marshaled fail
+	w = serve(h, `[{"jsonrpc":"2.0","method":"bad","id":1},{"jsonrpc":"2.0","method":"broken","id":2},{"jsonrpc":"2.0","method":"ping","id":3}]`)
+	assert.JSONEq(t, `[{"jsonrpc":"2.0-x","id":1,"error":{"code":-32603,"message":"internal error"}},{"jsonrpc":"2.0-x","id":2,"error":{"code":-32603,"message":"internal error"}},{"jsonrpc":"2.0-x","id":3,"result":"pong"}]`, w.Body.String())
+}
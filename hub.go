@@ -0,0 +1,95 @@
+package gojsonrpc
+
+import (
+	"sync"
+)
+
+// notification is a notification sent by the server
+type notification struct {
+	Version string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// hub tracks which WebSocket connections joined each topic
+type hub struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Conn]struct{}
+}
+
+func newHub() *hub {
+	return &hub{topics: make(map[string]map[*Conn]struct{})}
+}
+
+// Join adds the connection to topic, so it receives what is published to it until it leaves or closes
+func (ws *Conn) Join(topic string) error {
+	if ws == nil {
+		return ErrNotWebsocket
+	}
+	hub := ws.h.hub
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if ws.c.Err() != nil {
+		return ErrConnClosed
+	}
+	conns, ok := hub.topics[topic]
+	if !ok {
+		conns = make(map[*Conn]struct{})
+		hub.topics[topic] = conns
+	}
+	conns[ws] = struct{}{}
+	ws.topics[topic] = struct{}{}
+	return nil
+}
+
+// Leave removes the connection from topic
+func (ws *Conn) Leave(topic string) {
+	if ws == nil {
+		return
+	}
+	hub := ws.h.hub
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	hub.leaveLocked(ws, topic)
+}
+
+// leaveAll removes a closed connection from every topic
+func (hub *hub) leaveAll(ws *Conn) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for topic := range ws.topics {
+		hub.leaveLocked(ws, topic)
+	}
+}
+
+func (hub *hub) leaveLocked(ws *Conn, topic string) {
+	delete(ws.topics, topic)
+	conns := hub.topics[topic]
+	delete(conns, ws)
+	if len(conns) == 0 {
+		delete(hub.topics, topic)
+	}
+}
+
+// Publish sends a notification to every connection that joined topic and returns how many it was queued for; it never
+// waits, so a connection whose queue is full misses the notification, which only shows in the count
+func (h *Handler) Publish(topic string, method string, params interface{}) (int, error) {
+	msg, err := marshalMessage(notification{
+		Version: requestVersion,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	h.hub.mu.RLock()
+	defer h.hub.mu.RUnlock()
+	delivered := 0
+	for ws := range h.hub.topics[topic] {
+		if ws.trySend(msg) {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
@@ -0,0 +1,79 @@
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type TestRoomNamespace struct{}
+
+func (t *TestRoomNamespace) Join(ws *Conn, topic string) error {
+	return ws.Join(topic)
+}
+
+func (t *TestRoomNamespace) Leave(ws *Conn, topic string) {
+	ws.Leave(topic)
+}
+
+func TestPublish(t *testing.T) {
+	h := New(DefaultNext())
+	must(h.AddNamespace("room", &TestRoomNamespace{}))
+
+	call := func(t *testing.T, conn *websocket.Conn, method string, topic string) {
+		must(conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"`+method+`","params":["`+topic+`"],"id":1}`)))
+		var result Result
+		must(json.Unmarshal([]byte(readMessage(t, conn)), &result))
+		assert.Nil(t, result.Error)
+	}
+
+	// publish until the number delivered is as expected, since connections leave the hub as they close
+	publish := func(t *testing.T, expected int) {
+		deadline := time.Now().Add(time.Second)
+		for {
+			n, err := h.Publish("news", "news.posted", []string{"hello"})
+			assert.NoError(t, err)
+			if n == expected || time.Now().After(deadline) {
+				assert.Equal(t, expected, n)
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	a, doneA := dial(t, h)
+	defer doneA()
+	b, doneB := dial(t, h)
+	call(t, a, "room.Join", "news")
+	call(t, b, "room.Join", "news")
+
+	publish(t, 2)
+	for _, conn := range []*websocket.Conn{a, b} {
+		assert.JSONEq(t, `{"jsonrpc":"2.0","method":"news.posted","params":["hello"]}`, readMessage(t, conn))
+	}
+
+	call(t, a, "room.Leave", "news")
+	publish(t, 1)
+	readMessage(t, b)
+
+	doneB()
+	publish(t, 0)
+
+	n, err := h.Publish("news", "news.posted", func() {})
+	assert.Error(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestConnQueue(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	ws := &Conn{c: c, cancel: cancel, out: make(chan json.RawMessage, 1)}
+
+	assert.True(t, ws.trySend(json.RawMessage("1")))
+	assert.False(t, ws.trySend(json.RawMessage("2")), "queue is full")
+	<-ws.out
+	cancel()
+	assert.False(t, ws.trySend(json.RawMessage("3")), "connection is done")
+}
@@ -0,0 +1,82 @@
+package gojsonrpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// injectorFunc resolves an injected parameter for a call
+type injectorFunc func(c context.Context) (reflect.Value, error)
+
+// injectorRegistry maps parameter types to the providers that resolve them; injected parameters are not supplied by
+// the caller and do not appear in the method signature
+type injectorRegistry map[reflect.Type]injectorFunc
+
+// builtinInjectors are available to every method
+var builtinInjectors = injectorRegistry{
+	reflectionTypeContext: func(c context.Context) (reflect.Value, error) {
+		return reflect.ValueOf(&c).Elem(), nil
+	},
+	reflectionTypeRequest: func(c context.Context) (reflect.Value, error) {
+		r, _ := HTTPRequestFromContext(c)
+		return reflect.ValueOf(r), nil
+	},
+	reflect.TypeOf(CallInfo{}): func(c context.Context) (reflect.Value, error) {
+		info, _ := CallInfoFromContext(c)
+		return reflect.ValueOf(info), nil
+	},
+	reflect.TypeOf((*Response)(nil)): func(c context.Context) (reflect.Value, error) {
+		r, _ := ResponseFromContext(c)
+		return reflect.ValueOf(r), nil
+	},
+	reflect.TypeOf((*Conn)(nil)): func(c context.Context) (reflect.Value, error) {
+		ws, _ := ConnFromContext(c)
+		return reflect.ValueOf(ws), nil
+	},
+}
+
+func (r injectorRegistry) lookup(t reflect.Type) (injectorFunc, bool) {
+	if inject, ok := r[t]; ok {
+		return inject, true
+	}
+	inject, ok := builtinInjectors[t]
+	return inject, ok
+}
+
+// WithInjector registers a provider for a parameter type, so methods can take it as a parameter without the caller
+// sending it; the provider is a func(context.Context) T or func(context.Context) (T, error) called for every call,
+// and an error from it is returned to the caller in the same way as an error from the method
+// Injectors must be registered before the methods that use them
+func WithInjector(provider interface{}) Option {
+	inject, t, err := newInjector(provider)
+	if err != nil {
+		panic(err)
+	}
+	return func(h *Handler) {
+		h.injectors[t] = inject
+	}
+}
+
+func newInjector(provider interface{}) (injectorFunc, reflect.Type, error) {
+	v := reflect.ValueOf(provider)
+	if !v.IsValid() || v.Kind() != reflect.Func {
+		return nil, nil, fmt.Errorf("injector must be a function; got %v", v)
+	}
+	t := v.Type()
+	if t.NumIn() != 1 || t.In(0) != reflectionTypeContext {
+		return nil, nil, fmt.Errorf("injector must take a single context.Context; got %v", t)
+	}
+	hasError := t.NumOut() == 2 && t.Out(1) == reflectionTypeError
+	if t.NumOut() != 1 && !hasError {
+		return nil, nil, fmt.Errorf("injector must return a value, or a value and an error; got %v", t)
+	}
+	inject := func(c context.Context) (reflect.Value, error) {
+		out := v.Call([]reflect.Value{reflect.ValueOf(&c).Elem()})
+		if hasError && !out[1].IsNil() {
+			return reflect.Value{}, out[1].Interface().(error)
+		}
+		return out[0], nil
+	}
+	return inject, t.Out(0), nil
+}
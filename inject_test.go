@@ -0,0 +1,84 @@
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type TestPrincipal struct {
+	Name string
+}
+
+type testPrincipalKey struct{}
+
+type TestLogger struct {
+	prefix string
+}
+
+type TestInjectNamespace struct{}
+
+func (t *TestInjectNamespace) Whoami(p *TestPrincipal, greeting string) string {
+	return greeting + " " + p.Name
+}
+
+func (t *TestInjectNamespace) RPCParameterNames(method string) []string {
+	if method == "Whoami" {
+		return []string{"greeting"}
+	}
+	return nil
+}
+
+func (t *TestInjectNamespace) Log(l TestLogger, r *http.Request, info CallInfo) string {
+	return l.prefix + info.Method
+}
+
+func TestInjectors(t *testing.T) {
+	principal := func(c context.Context) (*TestPrincipal, error) {
+		p, ok := c.Value(testPrincipalKey{}).(*TestPrincipal)
+		if !ok {
+			return nil, &Error{Code: CodeUnauthorized, Message: "no principal"}
+		}
+		return p, nil
+	}
+	logger := func(c context.Context) TestLogger {
+		return TestLogger{"log: "}
+	}
+
+	h := New(DefaultNext(), WithInjector(principal), WithInjector(logger))
+	must(h.AddNamespace("test", &TestInjectNamespace{}))
+
+	callCase := func(c context.Context, req Request, expectedResult interface{}, expectedError *Error) func(t *testing.T) {
+		return func(t *testing.T) {
+			results, err := h.processRequests(c, []Request{req})
+			assert.NoError(t, err)
+			if assert.Len(t, results, 1) {
+				assert.Equal(t, expectedResult, results[0].Result)
+				assert.Equal(t, expectedError, results[0].Error)
+			}
+		}
+	}
+
+	withPrincipal := context.WithValue(context.Background(), testPrincipalKey{}, &TestPrincipal{"ada"})
+
+	t.Run("Positional", callCase(withPrincipal, Request{MethodName: "test.Whoami", Parameters: jsonParameterize([]interface{}{"hi"}), ID: 1}, "hi ada", nil))
+	t.Run("Named", callCase(withPrincipal, Request{MethodName: "test.Whoami", NamedParameters: map[string]json.RawMessage{"greeting": json.RawMessage(`"hey"`)}, ID: 1}, "hey ada", nil))
+	t.Run("ProviderError", callCase(context.Background(), Request{MethodName: "test.Whoami", Parameters: jsonParameterize([]interface{}{"hi"}), ID: 1}, nil, &Error{Code: CodeUnauthorized, Message: "no principal"}))
+	t.Run("Builtins", callCase(context.Background(), Request{MethodName: "test.Log", ID: 1}, "log: test.Log", nil))
+
+	t.Run("Signature", func(t *testing.T) {
+		results, _ := h.processRequests(withPrincipal, []Request{{MethodName: "test.Whoami", ID: 1}})
+		assert.Equal(t, &Error{Code: CodeInvalidParams, Message: "parameters should be (string)"}, results[0].Error)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, provider := range []interface{}{5, func() int { return 1 }, func(c context.Context) {}, func(c context.Context) (int, int) { return 1, 1 }} {
+			_, _, err := newInjector(provider)
+			assert.Error(t, err)
+		}
+		assert.Panics(t, func() { WithInjector(errors.New("nope")) })
+	})
+}
@@ -0,0 +1,30 @@
+package gojsonrpc
+
+import (
+	"context"
+)
+
+// Invoker runs a request and returns its result or error
+type Invoker func(c context.Context, req *Request) (interface{}, *Error)
+
+// Interceptor wraps every method call, including calls to methods that do not exist; it can inspect or change the
+// request, short-circuit by not calling next, or change the result and error that next returns
+type Interceptor func(c context.Context, req *Request, next Invoker) (interface{}, *Error)
+
+// WithInterceptors adds interceptors around every method call; the first interceptor added is the outermost
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(h *Handler) {
+		h.interceptors = append(h.interceptors, interceptors...)
+	}
+}
+
+// chainInterceptors wraps invoker so the interceptors are called in order before it
+func chainInterceptors(interceptors []Interceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(c context.Context, req *Request) (interface{}, *Error) {
+			return interceptor(c, req, next)
+		}
+	}
+	return invoker
+}
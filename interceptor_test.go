@@ -0,0 +1,46 @@
+package gojsonrpc
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInterceptors(t *testing.T) {
+	var calls []string
+	record := func(name string) Interceptor {
+		return func(c context.Context, req *Request, next Invoker) (interface{}, *Error) {
+			calls = append(calls, name+" "+req.MethodName)
+			return next(c, req)
+		}
+	}
+	shortCircuit := func(c context.Context, req *Request, next Invoker) (interface{}, *Error) {
+		if req.MethodName == "cached" {
+			return "from cache", nil
+		}
+		return next(c, req)
+	}
+	changeResult := func(c context.Context, req *Request, next Invoker) (interface{}, *Error) {
+		result, err := next(c, req)
+		if err != nil && err.Code == CodeMethodNotFound {
+			return nil, &Error{Code: 404, Message: "no such method " + req.MethodName}
+		}
+		return result, err
+	}
+
+	h := New(DefaultNext(), WithInterceptors(record("first"), record("second"), shortCircuit, changeResult), WithSequentialBatches())
+	must(h.AddMethod("add", func(a int, b int) int { return a + b }))
+
+	results, err := h.processRequests(context.Background(), []Request{
+		{MethodName: "add", Parameters: jsonParameterize([]interface{}{1, 2}), ID: 1},
+		{MethodName: "cached", ID: 2},
+		{MethodName: "missing", ID: 3},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, results, 3) {
+		assert.Equal(t, 3, results[0].Result)
+		assert.Equal(t, "from cache", results[1].Result)
+		assert.Equal(t, &Error{Code: 404, Message: "no such method missing"}, results[2].Error)
+	}
+	assert.Equal(t, []string{"first add", "second add", "first cached", "second cached", "first missing", "second missing"}, calls)
+}
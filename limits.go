@@ -0,0 +1,94 @@
+package gojsonrpc
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// WithBatchParallelism limits how many requests of a single batch run at the same time; 0 runs them all at once
+func WithBatchParallelism(n int) Option {
+	return func(h *Handler) {
+		h.batchParallelism = n
+	}
+}
+
+// WithMaxBatchSize rejects batches with more than n requests with a single invalid request error
+func WithMaxBatchSize(n int) Option {
+	return func(h *Handler) {
+		h.maxBatchSize = n
+	}
+}
+
+// WithMaxConcurrency limits how many calls the handler runs at the same time, across all requests and connections;
+// calls over the limit are answered with a server busy error. 0 means no limit
+func WithMaxConcurrency(n int) Option {
+	return func(h *Handler) {
+		if n <= 0 {
+			h.inFlight = nil
+			return
+		}
+		h.inFlight = make(chan struct{}, n)
+	}
+}
+
+// batchTooLarge is the single error that replaces a batch with more than max requests
+func batchTooLarge(max int) Request {
+	return newInvalidRequest(fmt.Sprintf("batch too large; at most %d requests", max))
+}
+
+// acquireCall reserves a slot for a call, returning false if the handler is at its limit
+func (h *Handler) acquireCall() bool {
+	if h.inFlight == nil {
+		return true
+	}
+	select {
+	case h.inFlight <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (h *Handler) releaseCall() {
+	if h.inFlight != nil {
+		<-h.inFlight
+	}
+}
+
+// callSlot is a call's share of WithMaxConcurrency; it is released when its last holder is done, so a call abandoned
+// after its deadline keeps it until the method returns
+type callSlot struct {
+	h       *Handler
+	holders int32
+}
+
+type callSlotKey struct{}
+
+func withCallSlot(c context.Context, slot *callSlot) context.Context {
+	return context.WithValue(c, callSlotKey{}, slot)
+}
+
+func callSlotFromContext(c context.Context) *callSlot {
+	slot, _ := c.Value(callSlotKey{}).(*callSlot)
+	return slot
+}
+
+func (s *callSlot) hold() {
+	if s != nil {
+		atomic.AddInt32(&s.holders, 1)
+	}
+}
+
+func (s *callSlot) release() {
+	if s != nil && atomic.AddInt32(&s.holders, -1) == 0 {
+		s.h.releaseCall()
+	}
+}
+
+// WithMaxBodySize limits HTTP request bodies to n bytes; larger bodies are passed to HandlerNext.RequestTooLarge
+func WithMaxBodySize(n int64) Option {
+	return func(h *Handler) {
+		h.maxBodySize = n
+	}
+}
@@ -0,0 +1,139 @@
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	t.Run("MaxBatchSize", func(t *testing.T) {
+		h := New(DefaultNext(), WithMaxBatchSize(2))
+		must(h.AddMethod("ping", func() string { return "pong" }))
+
+		w := serve(h, `[{"jsonrpc":"2.0","method":"ping","id":1},{"jsonrpc":"2.0","method":"ping","id":2}]`)
+		var results []Result
+		must(json.Unmarshal(w.Body.Bytes(), &results))
+		assert.Len(t, results, 2)
+
+		w = serve(h, `[{"jsonrpc":"2.0","method":"ping","id":1},{"jsonrpc":"2.0","method":"ping","id":2},{"jsonrpc":"2.0","method":"ping","id":3}]`)
+		var result Result
+		must(json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, &Error{Code: CodeInvalidRequest, Message: "invalid request; batch too large; at most 2 requests"}, result.Error)
+	})
+
+	t.Run("BatchParallelism", func(t *testing.T) {
+		var running, peak int32
+		h := New(DefaultNext(), WithBatchParallelism(3))
+		must(h.AddMethod("work", func() bool {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return true
+		}))
+		var requests []Request
+		for i := 0; i < 12; i++ {
+			requests = append(requests, Request{MethodName: "work", ID: i})
+		}
+		results, err := h.processRequests(context.Background(), requests)
+		assert.NoError(t, err)
+		assert.Len(t, results, 12)
+		for i, r := range results {
+			assert.Equal(t, i, r.ID)
+		}
+		assert.True(t, peak <= 3, "peak was %d", peak)
+	})
+
+	t.Run("MaxConcurrency", func(t *testing.T) {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		h := New(DefaultNext(), WithMaxConcurrency(1))
+		must(h.AddMethod("block", func() bool {
+			close(started)
+			<-release
+			return true
+		}))
+		must(h.AddMethod("ping", func() string { return "pong" }))
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, _ := h.processRequests(context.Background(), []Request{{MethodName: "block", ID: 1}})
+			assert.Equal(t, true, results[0].Result)
+		}()
+		<-started
+
+		results, _ := h.processRequests(context.Background(), []Request{{MethodName: "ping", ID: 2}})
+		if assert.NotNil(t, results[0].Error) {
+			assert.Equal(t, CodeServerBusy, results[0].Error.Code)
+		}
+
+		close(release)
+		wg.Wait()
+		results, _ = h.processRequests(context.Background(), []Request{{MethodName: "ping", ID: 3}})
+		assert.Equal(t, "pong", results[0].Result)
+	})
+
+	t.Run("MaxConcurrency/Timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		returned := make(chan struct{})
+		h := New(DefaultNext(), WithMaxConcurrency(1), WithTimeout(5*time.Millisecond))
+		// stuck ignores its context, so it keeps running after it times out
+		must(h.AddMethod("stuck", func() bool {
+			defer close(returned)
+			<-release
+			return true
+		}))
+		must(h.AddMethod("ping", func() string { return "pong" }))
+
+		results, _ := h.processRequests(context.Background(), []Request{{MethodName: "stuck", ID: 1}})
+		assert.Equal(t, CodeTimeout, results[0].Error.Code)
+
+		results, _ = h.processRequests(context.Background(), []Request{{MethodName: "ping", ID: 2}})
+		if assert.NotNil(t, results[0].Error) {
+			assert.Equal(t, CodeServerBusy, results[0].Error.Code)
+		}
+
+		close(release)
+		<-returned
+		// the slot is released just after the method returns
+		assert.Eventually(t, func() bool {
+			results, _ = h.processRequests(context.Background(), []Request{{MethodName: "ping", ID: 3}})
+			return results[0].Result == "pong"
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("MaxConcurrency/Unlimited", func(t *testing.T) {
+		for _, n := range []int{0, -1} {
+			h := New(DefaultNext(), WithMaxConcurrency(n))
+			must(h.AddMethod("ping", func() string { return "pong" }))
+			results, _ := h.processRequests(context.Background(), []Request{{MethodName: "ping", ID: 1}})
+			assert.Equal(t, "pong", results[0].Result, "n = %d", n)
+			assert.Nil(t, results[0].Error, "n = %d", n)
+		}
+	})
+}
+
+func TestMaxBodySize(t *testing.T) {
+	h := New(DefaultNext(), WithMaxBodySize(64))
+	must(h.AddMethod("echo", func(s string) string { return s }))
+
+	w := serve(h, `{"jsonrpc":"2.0","method":"echo","params":["short"],"id":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(h, `{"jsonrpc":"2.0","method":"echo","params":["`+strings.Repeat("a", 100)+`"],"id":1}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
@@ -0,0 +1,179 @@
+package gojsonrpc
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsCollector receives measurements from a handler; it must be safe for concurrent use
+type MetricsCollector interface {
+	// ObserveCall is called after every call; code is 0 when the call succeeded
+	ObserveCall(method string, code int, duration time.Duration)
+	// ObserveBatch is called with the number of requests in each HTTP body or WebSocket message
+	ObserveBatch(size int)
+	// AddInFlight tracks HTTP requests and WebSocket connections currently being served
+	AddInFlight(transport Transport, delta int)
+}
+
+// WithMetrics reports call, batch and connection measurements to collector
+func WithMetrics(collector MetricsCollector) Option {
+	return func(h *Handler) {
+		h.metrics = collector
+	}
+}
+
+// unknownMethod labels calls to methods that are not registered, so arbitrary names do not become metric labels
+const unknownMethod = "<unknown>"
+
+type noMetrics struct{}
+
+func (noMetrics) ObserveCall(method string, code int, duration time.Duration) {}
+func (noMetrics) ObserveBatch(size int)                                       {}
+func (noMetrics) AddInFlight(transport Transport, delta int)                  {}
+
+var (
+	defaultDurationBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+	defaultBatchBuckets    = []float64{1, 2, 5, 10, 20, 50, 100, 500}
+)
+
+// PrometheusMetrics is a MetricsCollector that serves what it collects in the Prometheus text exposition format
+type PrometheusMetrics struct {
+	mu        sync.Mutex
+	calls     map[promCallKey]uint64
+	durations map[string]*promHistogram
+	batches   *promHistogram
+	inFlight  map[Transport]int64
+}
+
+type promCallKey struct {
+	method string
+	code   int
+}
+
+type promHistogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		calls:     make(map[promCallKey]uint64),
+		durations: make(map[string]*promHistogram),
+		batches:   newPromHistogram(defaultBatchBuckets),
+		inFlight:  make(map[Transport]int64),
+	}
+}
+
+func newPromHistogram(buckets []float64) *promHistogram {
+	return &promHistogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (p *promHistogram) observe(v float64) {
+	for i, upper := range p.buckets {
+		if v <= upper {
+			p.counts[i]++
+		}
+	}
+	p.sum += v
+	p.count++
+}
+
+func (m *PrometheusMetrics) ObserveCall(method string, code int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls[promCallKey{method, code}]++
+	d, ok := m.durations[method]
+	if !ok {
+		d = newPromHistogram(defaultDurationBuckets)
+		m.durations[method] = d
+	}
+	d.observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) ObserveBatch(size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches.observe(float64(size))
+}
+
+func (m *PrometheusMetrics) AddInFlight(transport Transport, delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight[transport] += int64(delta)
+}
+
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(m.render()))
+}
+
+// render writes every metric, sorted by labels so the output is stable
+func (m *PrometheusMetrics) render() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+
+	b.WriteString("# HELP jsonrpc_calls_total JSON-RPC calls by method and error code; code 0 is success.\n")
+	b.WriteString("# TYPE jsonrpc_calls_total counter\n")
+	keys := make([]promCallKey, 0, len(m.calls))
+	for k := range m.calls {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].code < keys[j].code
+	})
+	for _, k := range keys {
+		fmt.Fprintf(&b, "jsonrpc_calls_total{method=\"%s\",code=\"%d\"} %d\n", promEscape(k.method), k.code, m.calls[k])
+	}
+
+	b.WriteString("# HELP jsonrpc_call_duration_seconds JSON-RPC call latency by method.\n")
+	b.WriteString("# TYPE jsonrpc_call_duration_seconds histogram\n")
+	methods := make([]string, 0, len(m.durations))
+	for method := range m.durations {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		m.durations[method].render(&b, "jsonrpc_call_duration_seconds", fmt.Sprintf("method=\"%s\",", promEscape(method)))
+	}
+
+	b.WriteString("# HELP jsonrpc_batch_size Requests per HTTP body or WebSocket message.\n")
+	b.WriteString("# TYPE jsonrpc_batch_size histogram\n")
+	m.batches.render(&b, "jsonrpc_batch_size", "")
+
+	b.WriteString("# HELP jsonrpc_in_flight HTTP requests and WebSocket connections being served.\n")
+	b.WriteString("# TYPE jsonrpc_in_flight gauge\n")
+	for _, transport := range []Transport{TransportHTTP, TransportWebsocket} {
+		fmt.Fprintf(&b, "jsonrpc_in_flight{transport=\"%s\"} %d\n", transport, m.inFlight[transport])
+	}
+
+	return b.String()
+}
+
+func (p *promHistogram) render(b *strings.Builder, name string, labels string) {
+	for i, upper := range p.buckets {
+		fmt.Fprintf(b, "%s_bucket{%sle=\"%g\"} %d\n", name, labels, upper, p.counts[i])
+	}
+	fmt.Fprintf(b, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, p.count)
+	labels = strings.TrimSuffix(labels, ",")
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(b, "%s_sum%s %g\n", name, labels, p.sum)
+	fmt.Fprintf(b, "%s_count%s %d\n", name, labels, p.count)
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promEscape(s string) string {
+	return promEscaper.Replace(s)
+}
@@ -0,0 +1,47 @@
+package gojsonrpc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	m := NewPrometheusMetrics()
+	h := New(DefaultNext(), WithMetrics(m))
+	must(h.AddMethod("ping", func() string { return "pong" }))
+
+	serve(h, `{"jsonrpc":"2.0","method":"ping","id":1}`)
+	serve(h, `[{"jsonrpc":"2.0","method":"ping","id":1},{"jsonrpc":"2.0","method":"nope","id":2}]`)
+	m.AddInFlight(TransportWebsocket, 1)
+
+	r, err := http.NewRequest("GET", "/metrics", nil)
+	must(err)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	body := w.Body.String()
+
+	assert.Equal(t, "text/plain; version=0.0.4", w.Header().Get("Content-Type"))
+	assert.Contains(t, body, "# TYPE jsonrpc_calls_total counter\n")
+	assert.Contains(t, body, `jsonrpc_calls_total{method="<unknown>",code="-32601"} 1`+"\n")
+	assert.Contains(t, body, `jsonrpc_calls_total{method="ping",code="0"} 2`+"\n")
+	assert.Contains(t, body, `jsonrpc_call_duration_seconds_bucket{method="ping",le="+Inf"} 2`+"\n")
+	assert.Contains(t, body, `jsonrpc_call_duration_seconds_count{method="ping"} 2`+"\n")
+	assert.Contains(t, body, `jsonrpc_batch_size_bucket{le="1"} 1`+"\n")
+	assert.Contains(t, body, `jsonrpc_batch_size_bucket{le="2"} 2`+"\n")
+	assert.Contains(t, body, "jsonrpc_batch_size_sum 3\n")
+	assert.Contains(t, body, `jsonrpc_in_flight{transport="http"} 0`+"\n")
+	assert.Contains(t, body, `jsonrpc_in_flight{transport="websocket"} 1`+"\n")
+}
+
+func TestPromHistogram(t *testing.T) {
+	p := newPromHistogram([]float64{0.1, 1})
+	p.observe((50 * time.Millisecond).Seconds())
+	p.observe(0.5)
+	p.observe(3)
+	assert.Equal(t, []uint64{1, 2}, p.counts)
+	assert.Equal(t, uint64(3), p.count)
+	assert.InDelta(t, 3.55, p.sum, 0.0001)
+}
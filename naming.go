@@ -0,0 +1,69 @@
+package gojsonrpc
+
+import (
+	"strings"
+	"unicode"
+)
+
+// exposes checks if a Go method of a namespace should be registered
+func (config methodConfig) exposes(method string) bool {
+	if reservedMethodNames[method] || config.exclude[method] {
+		return false
+	}
+	if config.iface != nil {
+		if _, ok := config.iface.MethodByName(method); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// methodName is the RPC name for a Go method of a namespace
+func (config methodConfig) methodName(method string) string {
+	if config.nameTransform == nil {
+		return method
+	}
+	return config.nameTransform(method)
+}
+
+// LowerCamelCase converts a Go name to lower camel case, so "GetProfile" becomes "getProfile", "HTTPStatus" becomes
+// "httpStatus" and "IDs" becomes "ids"
+func LowerCamelCase(name string) string {
+	original := []rune(name)
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		// keep the last capital of an initialism when it starts the next word, unless it is followed by a plural s
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !isPluralInitialism(original, i+1) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// isPluralInitialism checks if the rune at i is an s making the initialism before it plural, as in "IDs" or "URLsByID"
+func isPluralInitialism(runes []rune, i int) bool {
+	return i >= 2 && runes[i] == 's' && unicode.IsUpper(runes[i-1]) && unicode.IsUpper(runes[i-2]) &&
+		(i+1 == len(runes) || !unicode.IsLower(runes[i+1]))
+}
+
+// SnakeCase converts a Go name to snake case, so "GetProfile" becomes "get_profile" and "UserID" becomes "user_id"
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			startsWord := unicode.IsLower(prev) || unicode.IsDigit(prev)
+			endsInitialism := unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !isPluralInitialism(runes, i+1)
+			if startsWord || endsInitialism {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package gojsonrpc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type TestUserAPI interface {
+	GetProfile(id int) string
+}
+
+type TestUserNamespace struct{}
+
+func (t *TestUserNamespace) GetProfile(id int) string {
+	return "profile"
+}
+
+func (t *TestUserNamespace) HTTPStatus() int {
+	return 200
+}
+
+func (t *TestUserNamespace) Helper() {}
+
+func (t *TestUserNamespace) RPCExcludedMethods() []string {
+	return []string{"Helper"}
+}
+
+func TestNameTransforms(t *testing.T) {
+	transformCase := func(transform func(string) string, input string, expected string) func(t *testing.T) {
+		return func(t *testing.T) {
+			assert.Equal(t, expected, transform(input))
+		}
+	}
+
+	t.Run("LowerCamelCase/Word", transformCase(LowerCamelCase, "GetProfile", "getProfile"))
+	t.Run("LowerCamelCase/Initialism", transformCase(LowerCamelCase, "HTTPStatus", "httpStatus"))
+	t.Run("LowerCamelCase/AllCaps", transformCase(LowerCamelCase, "ID", "id"))
+	t.Run("SnakeCase/Word", transformCase(SnakeCase, "GetProfile", "get_profile"))
+	t.Run("SnakeCase/Initialism", transformCase(SnakeCase, "HTTPStatus", "http_status"))
+	t.Run("SnakeCase/TrailingInitialism", transformCase(SnakeCase, "UserID", "user_id"))
+	t.Run("SnakeCase/Digits", transformCase(SnakeCase, "Get2Factor", "get2_factor"))
+
+	initialisms := []struct {
+		input, lowerCamel, snake string
+	}{
+		{"IDs", "ids", "ids"},
+		{"HTTPGet", "httpGet", "http_get"},
+		{"URLsByID", "urlsByID", "urls_by_id"},
+		{"IDsAndNames", "idsAndNames", "ids_and_names"},
+		{"IDList", "idList", "id_list"},
+		{"GetIDs", "getIDs", "get_ids"},
+		{"ServeHTTP", "serveHTTP", "serve_http"},
+		{"A", "a", "a"},
+		{"Ab", "ab", "ab"},
+		{"As", "as", "as"},
+		{"", "", ""},
+	}
+	for _, c := range initialisms {
+		t.Run("LowerCamelCase/"+c.input, transformCase(LowerCamelCase, c.input, c.lowerCamel))
+		t.Run("SnakeCase/"+c.input, transformCase(SnakeCase, c.input, c.snake))
+	}
+}
+
+func TestNamespaceOptions(t *testing.T) {
+	methodsCase := func(expected []string, handlerOptions []Option, options ...MethodOption) func(t *testing.T) {
+		return func(t *testing.T) {
+			h := New(DefaultNext(), handlerOptions...)
+			must(h.AddNamespace("user", &TestUserNamespace{}, options...))
+			assert.Equal(t, expected, h.Methods())
+		}
+	}
+
+	t.Run("Default", methodsCase([]string{"user.GetProfile", "user.HTTPStatus"}, nil))
+	t.Run("LowerCamelCase", methodsCase([]string{"user.getProfile", "user.httpStatus"}, nil, MethodNames(LowerCamelCase)))
+	t.Run("SnakeCase/Separator", methodsCase([]string{"user_get_profile", "user_http_status"}, nil, MethodNames(SnakeCase), Separator("_")))
+	t.Run("Exclude", methodsCase([]string{"user.GetProfile"}, nil, Exclude("HTTPStatus")))
+	t.Run("AsInterface", methodsCase([]string{"user.GetProfile"}, nil, AsInterface((*TestUserAPI)(nil))))
+	t.Run("HandlerDefaults", methodsCase([]string{"user/getProfile", "user/httpStatus"}, []Option{WithNamespaceOptions(MethodNames(LowerCamelCase), Separator("/"))}))
+	t.Run("HandlerDefaults/Override", methodsCase([]string{"user.getProfile", "user.httpStatus"}, []Option{WithNamespaceOptions(MethodNames(LowerCamelCase), Separator("/"))}, Separator(".")))
+
+	t.Run("AsInterface/NotImplemented", func(t *testing.T) {
+		h := New(DefaultNext())
+		assert.Error(t, h.AddNamespace("user", &TestFeatureNamespace{}, AsInterface((*TestUserAPI)(nil))))
+	})
+}
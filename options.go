@@ -0,0 +1,134 @@
+package gojsonrpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Option configures a Handler when it is created with New
+type Option func(*Handler)
+
+// WithSpecErrors answers unparseable bodies and invalid requests with JSON-RPC error objects (-32700 and -32600)
+// instead of passing them to HandlerNext
+func WithSpecErrors() Option {
+	return func(h *Handler) {
+		h.specErrors = true
+	}
+}
+
+// WithSequentialBatches runs the requests in a batch one after another, in the order they were sent, instead of in parallel
+func WithSequentialBatches() Option {
+	return WithBatchParallelism(1)
+}
+
+// PanicHandler receives the value and stack of a panic recovered from a method call
+type PanicHandler func(c context.Context, req *Request, recovered interface{}, stack []byte)
+
+// WithPanicHandler reports panics recovered from method calls to fn instead of the log
+func WithPanicHandler(fn PanicHandler) Option {
+	return func(h *Handler) {
+		h.panicHandler = fn
+	}
+}
+
+// MethodOption configures a single method when it is registered
+type MethodOption func(*methodConfig)
+
+type methodConfig struct {
+	parameterNames []string
+	requirements   []string
+	timeout        time.Duration
+
+	// namespace only
+	nameTransform func(string) string
+	separator     string
+	exclude       map[string]bool
+	iface         reflect.Type
+}
+
+// newMethodConfig applies the defaults and then the options
+func newMethodConfig(defaults []MethodOption, options []MethodOption) methodConfig {
+	config := methodConfig{
+		separator: ".",
+		exclude:   make(map[string]bool),
+	}
+	for _, option := range defaults {
+		option(&config)
+	}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// ParameterNames declares the names of a method's parameters for AddMethod, allowing it to be called with by-name
+// params; injected parameters such as context.Context are not named. Namespaces declare names with ParameterNamer
+func ParameterNames(names ...string) MethodOption {
+	return func(config *methodConfig) {
+		config.parameterNames = names
+	}
+}
+
+// Require declares roles or scopes that the Authorizer must accept before the method is called; for a namespace they
+// apply to every method
+func Require(requirements ...string) MethodOption {
+	return func(config *methodConfig) {
+		config.requirements = append(config.requirements, requirements...)
+	}
+}
+
+// Timeout limits how long the method may run, overriding the handler's default timeout; for a namespace it applies
+// to every method
+func Timeout(timeout time.Duration) MethodOption {
+	return func(config *methodConfig) {
+		config.timeout = timeout
+	}
+}
+
+// MethodNames transforms the Go method names of a namespace into RPC method names, for example with LowerCamelCase or
+// SnakeCase
+func MethodNames(transform func(string) string) MethodOption {
+	return func(config *methodConfig) {
+		config.nameTransform = transform
+	}
+}
+
+// Separator sets what goes between the namespace and the method name; the default is "."
+func Separator(separator string) MethodOption {
+	return func(config *methodConfig) {
+		config.separator = separator
+	}
+}
+
+// Exclude keeps the named Go methods of a namespace from being exposed
+func Exclude(methods ...string) MethodOption {
+	return func(config *methodConfig) {
+		for _, m := range methods {
+			config.exclude[m] = true
+		}
+	}
+}
+
+// AsInterface only exposes the methods of a namespace that are part of an interface, given as a nil pointer to it,
+// for example AsInterface((*UserAPI)(nil))
+func AsInterface(iface interface{}) MethodOption {
+	t := reflect.TypeOf(iface)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("AsInterface expects a pointer to an interface; got %v", t))
+	}
+	return func(config *methodConfig) {
+		config.iface = t
+	}
+}
+
+// WithNamespaceOptions applies options to every namespace added to the handler, before the options given to AddNamespace
+func WithNamespaceOptions(options ...MethodOption) Option {
+	return func(h *Handler) {
+		h.namespaceOptions = append(h.namespaceOptions, options...)
+	}
+}
@@ -5,13 +5,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strings"
+	"time"
 )
 
 var (
 	reflectionTypeContext = reflect.TypeOf((*context.Context)(nil)).Elem()
 	reflectionTypeError   = reflect.TypeOf((*error)(nil)).Elem()
+	reflectionTypeRequest = reflect.TypeOf((*http.Request)(nil))
+
+	reflectionTypeRawMessage  = reflect.TypeOf(json.RawMessage{})
+	reflectionTypeUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
 )
 
 type parameterizedMethod struct {
@@ -22,9 +28,13 @@ type parameterizedMethod struct {
 	requiredArgumentCount int
 	outputArgumentCount   int
 	isLastArgumentError   bool
+	parameterNames        []string
+	errorMappings         *errorRegistry
+	requirements          []string
+	timeout               time.Duration
 }
 
-func newParameterizedMethod(m reflect.Value) (*parameterizedMethod, error) {
+func newParameterizedMethod(m reflect.Value, injectors injectorRegistry) (*parameterizedMethod, error) {
 	// create the analysis from here
 	t := m.Type()
 	var parameters []parameterizedMethodParameter
@@ -33,13 +43,13 @@ func newParameterizedMethod(m reflect.Value) (*parameterizedMethod, error) {
 	lenArgs := t.NumIn()
 	isVariadic := t.IsVariadic()
 	for i := 0; i < lenArgs; i++ {
-		p, err := newParameterizedMethodParameter(t.In(i), inputIndex, isVariadic && i == lenArgs-1)
+		p, err := newParameterizedMethodParameter(t.In(i), inputIndex, isVariadic && i == lenArgs-1, injectors)
 		if err != nil {
 			fmt.Printf("Error inside: %v\n", err)
 			return nil, err
 		}
 		parameters = append(parameters, p)
-		if !p.isContext {
+		if !p.isInjected {
 			publicParams = append(publicParams, p.typeName)
 			inputIndex++
 		}
@@ -51,19 +61,105 @@ func newParameterizedMethod(m reflect.Value) (*parameterizedMethod, error) {
 	outputArgumentCount := t.NumOut()
 	isLastArgumentError := outputArgumentCount > 0 && t.Out(outputArgumentCount-1).Implements(reflectionTypeError)
 
-	return &parameterizedMethod{t, m, parameters, strings.Join(publicParams, ", "), inputIndex, outputArgumentCount, isLastArgumentError}, nil
+	return &parameterizedMethod{t, m, parameters, strings.Join(publicParams, ", "), inputIndex, outputArgumentCount, isLastArgumentError, nil, nil, nil, 0}, nil
+}
+
+// inputParameters returns the parameters that are supplied by the caller, in order
+func (p parameterizedMethod) inputParameters() []parameterizedMethodParameter {
+	var inputs []parameterizedMethodParameter
+	for _, param := range p.parameters {
+		if !param.isInjected {
+			inputs = append(inputs, param)
+		}
+	}
+	return inputs
+}
+
+// setParameterNames declares the by-name params for the method; one name per caller supplied parameter
+func (p *parameterizedMethod) setParameterNames(names []string) error {
+	if len(names) != len(p.inputParameters()) {
+		return fmt.Errorf("expected %d parameter names for (%s); got %d", len(p.inputParameters()), p.signature, len(names))
+	}
+	p.parameterNames = names
+	return nil
+}
+
+// bindNamedParameters converts by-name params into positional params, either using the declared parameter names or
+// by passing the whole object through to a method that takes a single object
+func (p parameterizedMethod) bindNamedParameters(named map[string]json.RawMessage) ([]json.RawMessage, error) {
+	inputs := p.inputParameters()
+	if p.parameterNames == nil {
+		if len(inputs) == 1 && !inputs[0].isVariadic && isJSONObjectType(inputs[0].underlying) {
+			b, err := json.Marshal(named)
+			if err != nil {
+				return nil, err
+			}
+			return []json.RawMessage{b}, nil
+		}
+		return nil, errors.New("method does not accept named parameters")
+	}
+
+	var params []json.RawMessage
+	found := 0
+	for i, param := range inputs {
+		v, ok := named[p.parameterNames[i]]
+		if ok {
+			found++
+		}
+		if param.isVariadic {
+			if ok {
+				var rest []json.RawMessage
+				if err := json.Unmarshal(v, &rest); err != nil {
+					return nil, err
+				}
+				params = append(params, rest...)
+			}
+			continue
+		}
+		if !ok {
+			if param.underlying.Kind() != reflect.Ptr {
+				return nil, fmt.Errorf("missing required parameter %s", p.parameterNames[i])
+			}
+			v = json.RawMessage("null")
+		}
+		params = append(params, v)
+	}
+	if found != len(named) {
+		return nil, errors.New("unknown named parameter")
+	}
+	return params, nil
+}
+
+func isJSONObjectType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct || t.Kind() == reflect.Map
+}
+
+// CallNamed calls the method with by-name params
+func (p parameterizedMethod) CallNamed(c context.Context, named map[string]json.RawMessage) (interface{}, *Error) {
+	params, err := p.bindNamedParameters(named)
+	if err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("parameters should be (%s)", p.signature)}
+	}
+	return p.Call(c, params)
 }
 
 func (p parameterizedMethod) Call(c context.Context, params []json.RawMessage) (interface{}, *Error) {
 	var methodArgs []reflect.Value
 	var err error
 	if p.requiredArgumentCount > 0 && len(params) > p.requiredArgumentCount {
-		return nil, &Error{Code: -32602, Message: fmt.Sprintf("parameters should be (%s)", p.signature)}
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("parameters should be (%s)", p.signature)}
 	}
 	for _, param := range p.parameters {
 		methodArgs, err = param.marshal(c, methodArgs, params)
+		if err != nil && param.isInjected {
+			// the caller did not supply this parameter, so it is not their mistake
+			return nil, p.errorMappings.marshal(err)
+		}
 		if err != nil {
-			return nil, &Error{Code: -32602, Message: fmt.Sprintf("parameters should be (%s)", p.signature)}
+			return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("parameters should be (%s)", p.signature)}
 		}
 	}
 	returnValues := p.method.Call(methodArgs)
@@ -76,8 +172,15 @@ func (p parameterizedMethod) Call(c context.Context, params []json.RawMessage) (
 		var err *Error = nil
 		if p.isLastArgumentError {
 			// it is an error at the end
-			err = marshalError(returnValues[lenResults-1])
+			err = marshalError(returnValues[lenResults-1], p.errorMappings)
 			returnValues = returnValues[:lenResults-1]
+			if err != nil {
+				// a response carries either a result or an error, never both
+				for _, rv := range returnValues {
+					discardResult(rv.Interface())
+				}
+				return nil, err
+			}
 		}
 		var result interface{}
 		if len(returnValues) == 1 {
@@ -96,15 +199,20 @@ func (p parameterizedMethod) Call(c context.Context, params []json.RawMessage) (
 type parameterizedMethodParameter struct {
 	underlying  reflect.Type
 	isVariadic  bool
-	isContext   bool
+	isInjected  bool
+	inject      injectorFunc
 	sourceIndex int
 	typeName    string
 }
 
 func (p parameterizedMethodParameter) marshal(c context.Context, methodArgs []reflect.Value, params []json.RawMessage) ([]reflect.Value, error) {
 	lenArgs := len(params)
-	if p.isContext {
-		return append(methodArgs, reflect.ValueOf(c)), nil
+	if p.isInjected {
+		v, err := p.inject(c)
+		if err != nil {
+			return nil, err
+		}
+		return append(methodArgs, v), nil
 	}
 
 	if !p.isVariadic {
@@ -115,7 +223,7 @@ func (p parameterizedMethodParameter) marshal(c context.Context, methodArgs []re
 		if err != nil {
 			return nil, err
 		} else {
-			return append(methodArgs, reflect.ValueOf(v)), nil
+			return append(methodArgs, valueOf(p.underlying, v)), nil
 		}
 	}
 
@@ -125,20 +233,28 @@ func (p parameterizedMethodParameter) marshal(c context.Context, methodArgs []re
 		if err != nil {
 			return nil, err
 		} else {
-			methodArgs = append(methodArgs, reflect.ValueOf(v))
+			methodArgs = append(methodArgs, valueOf(p.underlying, v))
 		}
 	}
 
 	return methodArgs, nil
 }
 
-func newParameterizedMethodParameter(t reflect.Type, i int, isVariadic bool) (parameterizedMethodParameter, error) {
-	isContext := t == reflectionTypeContext
-	if isContext {
+// valueOf wraps a decoded parameter; a null decoded into an interface is nil, which still needs to be passed as a typed zero value
+func valueOf(t reflect.Type, v interface{}) reflect.Value {
+	if v == nil {
+		return reflect.Zero(t)
+	}
+	return reflect.ValueOf(v)
+}
+
+func newParameterizedMethodParameter(t reflect.Type, i int, isVariadic bool, injectors injectorRegistry) (parameterizedMethodParameter, error) {
+	if inject, ok := injectors.lookup(t); ok && !isVariadic {
 		return parameterizedMethodParameter{
 			underlying:  t,
 			isVariadic:  false,
-			isContext:   true,
+			isInjected:  true,
+			inject:      inject,
 			sourceIndex: i,
 			typeName:    "",
 		}, nil
@@ -161,7 +277,7 @@ func newParameterizedMethodParameter(t reflect.Type, i int, isVariadic bool) (pa
 	return parameterizedMethodParameter{
 		underlying:  t,
 		isVariadic:  isVariadic,
-		isContext:   false,
+		isInjected:  false,
 		sourceIndex: i,
 		typeName:    typename,
 	}, nil
@@ -170,6 +286,9 @@ func newParameterizedMethodParameter(t reflect.Type, i int, isVariadic bool) (pa
 // getJSONType returns the json type (one of object, array, bool, number, string)
 // for objects this includes the _optional_ name (so "object (ExampleStruct)" or "array (number)")
 func getJSONType(r reflect.Type) (string, error) {
+	if r == reflectionTypeRawMessage {
+		return "any", nil
+	}
 	switch r.Kind() {
 	case reflect.Int:
 		fallthrough
@@ -218,12 +337,31 @@ func getJSONType(r reflect.Type) (string, error) {
 			return "", err
 		}
 		return fmt.Sprintf("%s?", underlying), nil
+	case reflect.Interface:
+		// JSON can only be decoded into an empty interface
+		if r.NumMethod() == 0 {
+			return "any", nil
+		}
+		return "", errors.New("Unsupported Type")
+	}
+	if isJSONUnmarshaler(r) {
+		return "any", nil
 	}
 	return "", errors.New("Unsupported Type")
 }
 
+// isJSONUnmarshaler checks if the type decodes itself, regardless of its kind
+func isJSONUnmarshaler(t reflect.Type) bool {
+	return t.Implements(reflectionTypeUnmarshaler) || reflect.PtrTo(t).Implements(reflectionTypeUnmarshaler)
+}
+
 func marshalJSONType(t reflect.Type, v json.RawMessage) (interface{}, error) {
-	switch t.Kind() {
+	kind := t.Kind()
+	if isJSONUnmarshaler(t) {
+		// types that decode themselves are decoded the same way as a struct
+		kind = reflect.Struct
+	}
+	switch kind {
 	case reflect.Int:
 		fallthrough
 	case reflect.Int8:
@@ -254,6 +392,14 @@ func marshalJSONType(t reflect.Type, v json.RawMessage) (interface{}, error) {
 		fallthrough
 	case reflect.Struct:
 		fallthrough
+	case reflect.Array:
+		fallthrough
+	case reflect.Slice:
+		fallthrough
+	case reflect.Map:
+		fallthrough
+	case reflect.Interface:
+		fallthrough
 	case reflect.Ptr:
 		r := reflect.New(t)
 		if err := json.Unmarshal(v, r.Interface()); err != nil {
@@ -266,7 +412,7 @@ func marshalJSONType(t reflect.Type, v json.RawMessage) (interface{}, error) {
 		return r.Elem().Interface(), nil
 		// migrate to new parameter
 	}
-	return nil, nil
+	return nil, errors.New("Unsupported Type")
 }
 
 type parameterTypeMismatchError struct {
@@ -279,29 +425,29 @@ func (p *parameterTypeMismatchError) Error() string {
 
 func newParameterTypeMismatchError(unmarshal *json.UnmarshalTypeError) error {
 	var message string
+	typename := unmarshal.Type.Name()
+	if typename == "" {
+		// unnamed types such as []string or map[string]int
+		typename = unmarshal.Type.String()
+	}
 	if unmarshal.Field != "" {
-		message = fmt.Sprintf("expected %s at path \"%s\"; got %s", unmarshal.Type.Name(), unmarshal.Field, unmarshal.Value)
+		message = fmt.Sprintf("expected %s at path \"%s\"; got %s", typename, unmarshal.Field, unmarshal.Value)
 	} else {
-		message = fmt.Sprintf("expected %s; got %s", unmarshal.Type.Name(), unmarshal.Value)
+		message = fmt.Sprintf("expected %s; got %s", typename, unmarshal.Value)
 	}
 	return &parameterTypeMismatchError{
 		message,
 	}
 }
 
-func marshalError(e reflect.Value) *Error {
+func marshalError(e reflect.Value, mappings *errorRegistry) *Error {
 	i := e.Interface()
 	if i == nil {
 		return nil
 	}
 	switch v := i.(type) {
-	case *Error:
-		return v
 	case error:
-		return &Error{
-			Code:    -32000,
-			Message: v.Error(),
-		}
+		return mappings.marshal(v)
 	default:
 		panic(errors.New(fmt.Sprintf("Should not be here; should've checked to see if an error beforehand %v", v)))
 	}
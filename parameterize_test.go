@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"reflect"
 	"testing"
 )
@@ -75,6 +76,10 @@ func TestGetJSONType(t *testing.T) {
 	t.Run("CustomStruct", gjts(reflect.TypeOf(TestCustomStruct{}), "string", nil))
 	t.Run("Ptr/Int", gjts(reflect.PtrTo(reflect.TypeOf(int(5))), "number (int)?", nil))
 	t.Run("Ptr/String", gjts(reflect.PtrTo(reflect.TypeOf("1234")), "string?", nil))
+	t.Run("Interface", gjts(reflect.TypeOf((*interface{})(nil)).Elem(), "any", nil))
+	t.Run("RawMessage", gjts(reflect.TypeOf(json.RawMessage{}), "any", nil))
+	t.Run("Chan", gjts(reflect.TypeOf(make(chan int)), "", errors.New("Unsupported Type")))
+	t.Run("Interface/Methods", gjts(reflect.TypeOf((*io.Reader)(nil)).Elem(), "", errors.New("Unsupported Type")))
 	// interface, func, chan, complex64, complex128, unsafeptr
 }
 
@@ -110,6 +115,11 @@ func TestMarshalJSONType(t *testing.T) {
 	t.Run("Bool", passthroughCase(true))
 	t.Run("Struct", passthroughCase(TestJSONStruct{"1234"}))
 	t.Run("CustomStruct", passthroughCase(TestCustomStruct{"1234"})) // note that this one is going into a string in json and then back out
+	t.Run("Slice", passthroughCase([]string{"a", "b"}))
+	t.Run("Array", passthroughCase([2]int{1, 2}))
+	t.Run("Map", passthroughCase(map[string]int{"a": 1}))
+	t.Run("Slice/Struct", passthroughCase([]TestJSONStruct{{"1234"}}))
+	t.Run("RawMessage", passthroughCase(json.RawMessage(`{"a":[1,2]}`)))
 
 	pointerCase := func(
 		value interface{},
@@ -134,6 +144,12 @@ func TestMarshalJSONType(t *testing.T) {
 	t.Run("Ptr/Int", pointerCase(int(5), reflect.PtrTo(reflect.TypeOf(int(5)))))
 	t.Run("Ptr/IntNil", pointerCase(nil, reflect.PtrTo(reflect.TypeOf(int(5)))))
 
+	t.Run("Interface", func(t *testing.T) {
+		actualOutput, actualError := marshalJSONType(reflect.TypeOf((*interface{})(nil)).Elem(), json.RawMessage(`{"a":[1]}`))
+		assert.NoError(t, actualError)
+		assert.Equal(t, map[string]interface{}{"a": []interface{}{float64(1)}}, actualOutput)
+	})
+
 	errorCase := func(
 		input interface{},
 		expectedOutput interface{},
@@ -157,6 +173,8 @@ func TestMarshalJSONType(t *testing.T) {
 	t.Run("Struct/Nested", errorCase(TestBreakJSONStruct{float64(-9.9)}, TestJSONStruct{"1234"}, "expected string at path \"member\"; got number"))
 	t.Run("CustomStruct/CustomValidation", errorCase("a", TestCustomStruct{"a"}, "custom validation; must have length of at least 3"))
 	t.Run("CustomStruct/Number", errorCase(float64(-9.9), TestCustomStruct{"1234"}, "expected string; got number"))
+	t.Run("Slice/String", errorCase("testing", []string{}, "expected []string; got string"))
+	t.Run("Map/Value", errorCase(map[string]interface{}{"a": "b"}, map[string]int{}, "expected int at path \"a\"; got string"))
 }
 
 func TestNewParameterizedMethod(t *testing.T) {
@@ -167,7 +185,7 @@ func TestNewParameterizedMethod(t *testing.T) {
 	) func(t *testing.T) {
 		return func(t *testing.T) {
 			m := reflect.ValueOf(fn)
-			p, err := newParameterizedMethod(m)
+			p, err := newParameterizedMethod(m, nil)
 			assert.Nil(t, err)
 			assert.Equal(t, expectedSignature, p.signature)
 		}
@@ -177,6 +195,14 @@ func TestNewParameterizedMethod(t *testing.T) {
 	t.Run("Signature/Variadic", signatureCase(func(abc int, efg string, hij ...int) {}, "number (int), string, ...number (int)"))
 	t.Run("Signature/Context", signatureCase(func(c context.Context, abc int, efg string) {}, "number (int), string"))
 
+	t.Run("Register/Interface", func(t *testing.T) {
+		// a method taking an interface with methods cannot be called, so it is rejected when it is registered
+		_, err := newParameterizedMethod(reflect.ValueOf(func(r io.Reader) {}), nil)
+		assert.EqualError(t, err, "Unsupported Type")
+		_, err = newParameterizedMethod(reflect.ValueOf(func(v interface{}) {}), nil)
+		assert.NoError(t, err)
+	})
+
 	callCase := func(
 		fn interface{},
 		expectedOutput interface{},
@@ -184,7 +210,7 @@ func TestNewParameterizedMethod(t *testing.T) {
 	) func(t *testing.T) {
 		return func(t *testing.T) {
 			m := reflect.ValueOf(fn)
-			p, err := newParameterizedMethod(m)
+			p, err := newParameterizedMethod(m, nil)
 			assert.NoError(t, err)
 			parameters := jsonParameterize(params)
 			actualOutput, actualError := p.Call(context.Background(), parameters)
@@ -209,6 +235,9 @@ func TestNewParameterizedMethod(t *testing.T) {
 		return total
 	}, 7, 3, 3))
 
+	t.Run("Call/Slice", callCase(func(names []string) int { return len(names) }, 2, []string{"a", "b"}))
+	t.Run("Call/InterfaceNull", callCase(func(v interface{}) bool { return v == nil }, true, nil))
+
 	t.Run("Call/MultipleReturn", callCase(func(abc int) (int, int) {
 		return abc + 1, abc + 4
 	}, []interface{}{4, 7}, 3))
@@ -220,7 +249,7 @@ func TestNewParameterizedMethod(t *testing.T) {
 	) func(t *testing.T) {
 		return func(t *testing.T) {
 			m := reflect.ValueOf(fn)
-			p, err := newParameterizedMethod(m)
+			p, err := newParameterizedMethod(m, nil)
 			assert.NoError(t, err)
 			parameters := jsonParameterize(params)
 			actualOutput, actualError := p.Call(context.Background(), parameters)
@@ -236,4 +265,65 @@ func TestNewParameterizedMethod(t *testing.T) {
 	t.Run("Call/FlatTooManyArgs", callErrorCase(func(abc int) int { return abc + 1 }, Error{Code: -32602, Message: "parameters should be (number (int))"}, 5, 8))
 	t.Run("Call/UnknownInternalError", callErrorCase(func() error { return errors.New("what happened here") }, Error{Code: -32000, Message: "what happened here"}))
 	t.Run("Call/CustomInternalError", callErrorCase(func() error { return &Error{Code: -1000, Message: "what what"} }, Error{Code: -1000, Message: "what what"}))
+
+	namedCallCase := func(
+		fn interface{},
+		names []string,
+		expectedOutput interface{},
+		named map[string]interface{},
+	) func(t *testing.T) {
+		return func(t *testing.T) {
+			m := reflect.ValueOf(fn)
+			p, err := newParameterizedMethod(m, nil)
+			assert.NoError(t, err)
+			if names != nil {
+				assert.NoError(t, p.setParameterNames(names))
+			}
+			parameters := make(map[string]json.RawMessage)
+			for k, v := range named {
+				b, err := json.Marshal(v)
+				must(err)
+				parameters[k] = b
+			}
+			actualOutput, actualError := p.CallNamed(context.Background(), parameters)
+			assert.Equal(t, expectedOutput, actualOutput)
+			assert.Nil(t, actualError)
+		}
+	}
+
+	t.Run("CallNamed/Names", namedCallCase(func(a int, b int) int { return a - b }, []string{"a", "b"}, 2, map[string]interface{}{"b": 3, "a": 5}))
+	t.Run("CallNamed/Context", namedCallCase(func(c context.Context, a int) int { return a }, []string{"a"}, 5, map[string]interface{}{"a": 5}))
+	t.Run("CallNamed/OptionalMissing", namedCallCase(func(a int, b *int) bool { return b == nil }, []string{"a", "b"}, true, map[string]interface{}{"a": 5}))
+	t.Run("CallNamed/Variadic", namedCallCase(func(a int, rest ...int) int { return a + len(rest) }, []string{"a", "rest"}, 7, map[string]interface{}{"a": 5, "rest": []int{1, 1}}))
+	t.Run("CallNamed/Struct", namedCallCase(func(s TestJSONStruct) string { return s.Member }, nil, "1234", map[string]interface{}{"member": "1234"}))
+
+	namedCallErrorCase := func(
+		fn interface{},
+		names []string,
+		named map[string]interface{},
+	) func(t *testing.T) {
+		return func(t *testing.T) {
+			m := reflect.ValueOf(fn)
+			p, err := newParameterizedMethod(m, nil)
+			assert.NoError(t, err)
+			if names != nil {
+				assert.NoError(t, p.setParameterNames(names))
+			}
+			parameters := make(map[string]json.RawMessage)
+			for k, v := range named {
+				b, err := json.Marshal(v)
+				must(err)
+				parameters[k] = b
+			}
+			actualOutput, actualError := p.CallNamed(context.Background(), parameters)
+			assert.Nil(t, actualOutput)
+			if assert.NotNil(t, actualError) {
+				assert.Equal(t, -32602, actualError.Code)
+			}
+		}
+	}
+
+	t.Run("CallNamed/NoNames", namedCallErrorCase(func(a int, b int) int { return a - b }, nil, map[string]interface{}{"a": 5, "b": 3}))
+	t.Run("CallNamed/Missing", namedCallErrorCase(func(a int, b int) int { return a - b }, []string{"a", "b"}, map[string]interface{}{"a": 5}))
+	t.Run("CallNamed/Unknown", namedCallErrorCase(func(a int) int { return a }, []string{"a"}, map[string]interface{}{"a": 5, "c": 3}))
 }
@@ -1,43 +1,124 @@
 package gojsonrpc
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 )
 
-func parseRPCRequests(r *http.Request) ([]Request, error) {
+// parseRPCRequests reads the requests from the body, and whether they were sent as a batch
+// requests that are valid JSON but not valid requests are returned marked as invalid, so they can be answered individually
+func parseRPCRequests(r *http.Request, maxBatchSize int) ([]Request, bool, error) {
 
 	// do cursory type check
 	mimetype := r.Header.Get("Content-Type")
 	if mimetype != "application/json" {
-		return nil, errorBadContentType{}
+		return nil, false, errorBadContentType{}
 	}
 
-	var d json.RawMessage
-	// parse out the JSON body
-	err := json.NewDecoder(r.Body).Decode(&d)
+	return parseRPCBody(r.Body, maxBatchSize)
+}
+
+// parseRPCBody decodes a single request or a batch; batch elements are decoded one at a time straight from the stream,
+// and decoding stops as soon as a batch has more than maxBatchSize requests, unless it is 0
+func parseRPCBody(body io.Reader, maxBatchSize int) ([]Request, bool, error) {
+	br := bufio.NewReader(body)
+	first, err := peekJSONValue(br)
 	if err != nil {
-		return nil, errorInvalidJson{err, "wrapper"}
+		return nil, false, wrapDecodeError(err, "wrapper")
+	}
+
+	dec := json.NewDecoder(br)
+	if first != '[' {
+		req, err := decodeRPCRequest(dec, "singleton")
+		if err != nil {
+			return nil, false, err
+		}
+		return []Request{req}, false, nil
 	}
 
+	// opening bracket
+	if _, err := dec.Token(); err != nil {
+		return nil, false, wrapDecodeError(err, "array")
+	}
 	var requests []Request
-	d = bytes.TrimSpace(d)
+	for dec.More() {
+		if maxBatchSize > 0 && len(requests) == maxBatchSize {
+			// the rest of the batch is never decoded
+			return nil, false, errorBatchTooLarge{maxBatchSize}
+		}
+		req, err := decodeRPCRequest(dec, "array")
+		if err != nil {
+			return nil, false, err
+		}
+		requests = append(requests, req)
+	}
+	// closing bracket
+	if _, err := dec.Token(); err != nil {
+		return nil, false, wrapDecodeError(err, "array")
+	}
 
-	if bytes.HasPrefix(d, []byte{'['}) {
-		if err := json.Unmarshal(d, &requests); err != nil {
-			return nil, errorInvalidJson{err, "array"}
+	if len(requests) == 0 {
+		return nil, false, errorEmptyBatch{}
+	}
+	return requests, true, nil
+}
+
+// peekJSONValue returns the first byte of the next JSON value without consuming it
+func peekJSONValue(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
 		}
-	} else {
-		var r Request
-		if err := json.Unmarshal(d, &r); err != nil {
-			return nil, errorInvalidJson{err, "singleton"}
+		return b, br.UnreadByte()
+	}
+}
+
+// decodeRPCRequest decodes the next request from the stream; JSON that is well formed but not a request is returned
+// as an invalid request, while malformed JSON stops decoding
+func decodeRPCRequest(dec *json.Decoder, destination string) (Request, error) {
+	var r Request
+	if err := dec.Decode(&r); err != nil {
+		var shape errorRequestShape
+		if errors.As(err, &shape) {
+			return newInvalidRequest("not a request object"), nil
 		}
-		requests = append(requests, r)
+		return Request{}, wrapDecodeError(err, destination)
+	}
+	if r.MethodName == "" {
+		// without spec errors this is still called, and answered with method not found
+		r.invalid = newInvalidRequest("missing method").invalid
+		r.lenient = true
+		return r, nil
+	}
+	return r, nil
+}
+
+// wrapDecodeError classifies an error from reading the body
+func wrapDecodeError(err error, destination string) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return errorRequestTooLarge{tooLarge.Limit}
 	}
+	return errorInvalidJson{err, destination}
+}
 
-	return requests, nil
+func newInvalidRequest(reason string) Request {
+	return Request{
+		invalid: &Error{
+			Code:    CodeInvalidRequest,
+			Message: fmt.Sprintf("invalid request; %s", reason),
+		},
+	}
 }
 
 type errorBadContentType struct {
@@ -56,3 +137,108 @@ type errorInvalidJson struct {
 func (e errorInvalidJson) Error() string {
 	return fmt.Sprintf("Invalid JSON; an error occured parsing json into %s", e.destination)
 }
+
+type errorRequestTooLarge struct {
+	limit int64
+}
+
+func (e errorRequestTooLarge) Error() string {
+	return fmt.Sprintf("Request too large; the limit is %d bytes", e.limit)
+}
+
+// errorEmptyBatch is returned for a batch with no requests; with spec errors it is answered with a single invalid
+// request error, not an empty array
+type errorEmptyBatch struct{}
+
+func (e errorEmptyBatch) Error() string {
+	return "Empty batch"
+}
+
+// emptyBatch is the single error that answers an empty batch
+func emptyBatch() Request {
+	return newInvalidRequest("empty batch")
+}
+
+// errorBatchTooLarge is returned as soon as a batch has more requests than the limit; it is answered with a single
+// invalid request error
+type errorBatchTooLarge struct {
+	limit int
+}
+
+func (e errorBatchTooLarge) Error() string {
+	return fmt.Sprintf("Batch too large; the limit is %d requests", e.limit)
+}
+
+// errorRequestShape is returned when a value is valid JSON but not shaped like a request
+type errorRequestShape struct {
+	underlying error
+}
+
+func (e errorRequestShape) Error() string {
+	return fmt.Sprintf("Not a request; %v", e.underlying)
+}
+
+// wireRequest is the shape of a request on the wire; params stays raw until we know if it is an array or an object
+type wireRequest struct {
+	Version    string          `json:"jsonrpc"`
+	MethodName string          `json:"method"`
+	Parameters json.RawMessage `json:"params,omitempty"`
+	ID         json.RawMessage `json:"id,omitempty"`
+}
+
+func (r *Request) UnmarshalJSON(b []byte) error {
+	var w wireRequest
+	if err := json.Unmarshal(b, &w); err != nil {
+		return errorRequestShape{err}
+	}
+	*r = Request{
+		Version:    w.Version,
+		MethodName: w.MethodName,
+	}
+
+	// a request without an id member is a notification; an explicit null id is still a request
+	if len(w.ID) == 0 {
+		r.Notification = true
+	} else if err := json.Unmarshal(w.ID, &r.ID); err != nil {
+		return errorRequestShape{err}
+	}
+
+	params := bytes.TrimSpace(w.Parameters)
+	switch {
+	case len(params) == 0 || bytes.Equal(params, []byte("null")):
+		return nil
+	case params[0] == '[':
+		if err := json.Unmarshal(params, &r.Parameters); err != nil {
+			return errorRequestShape{err}
+		}
+		return nil
+	case params[0] == '{':
+		if err := json.Unmarshal(params, &r.NamedParameters); err != nil {
+			return errorRequestShape{err}
+		}
+		return nil
+	}
+	return errorRequestShape{errors.New("params must be an array or an object")}
+}
+
+func (r Request) MarshalJSON() ([]byte, error) {
+	w := wireRequest{
+		Version:    r.Version,
+		MethodName: r.MethodName,
+	}
+	var err error
+	if !r.Notification {
+		if w.ID, err = json.Marshal(r.ID); err != nil {
+			return nil, err
+		}
+	}
+	if r.NamedParameters != nil {
+		w.Parameters, err = json.Marshal(r.NamedParameters)
+	} else if r.Parameters != nil {
+		w.Parameters, err = json.Marshal(r.Parameters)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(w)
+}
@@ -48,7 +48,7 @@ func TestParseRPCRequests(t *testing.T) {
 
 		src := Request{Version: "2.0-x", MethodName: "test.Add", Parameters: jsonParameterize([]interface{}{float64(1), float64(2), float64(3)}), ID: float64(1)}
 		r := makerequest(src)
-		result, err := parseRPCRequests(r)
+		result, _, err := parseRPCRequests(r, 0)
 		assert.NoError(err)
 		assert.Len(result, 1)
 		assert.Equal(result[0].Version, src.Version)
@@ -62,7 +62,7 @@ func TestParseRPCRequests(t *testing.T) {
 
 		src := Request{Version: "2.0-x", MethodName: "test.Add", Parameters: jsonParameterize([]interface{}{float64(1), float64(2), float64(3)}), ID: float64(1)}
 		r := makerequest(src, src)
-		result, err := parseRPCRequests(r)
+		result, _, err := parseRPCRequests(r, 0)
 		assert.NoError(err)
 		assert.Len(result, 2)
 		assert.Equal(result[0].Version, src.Version)
@@ -75,13 +75,26 @@ func TestParseRPCRequests(t *testing.T) {
 		assert.Equal(result[1].ID, src.ID)
 	})
 
+	t.Run("named", func(t *testing.T) {
+		assert := assert.New(t)
+
+		r, err := http.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","method":"test.Sub","params":{"a":5,"b":3},"id":1}`))
+		r.Header.Set("Content-Type", "application/json")
+		must(err)
+		result, _, err := parseRPCRequests(r, 0)
+		assert.NoError(err)
+		assert.Len(result, 1)
+		assert.Nil(result[0].Parameters)
+		assert.Equal(map[string]json.RawMessage{"a": json.RawMessage("5"), "b": json.RawMessage("3")}, result[0].NamedParameters)
+	})
+
 	t.Run("BadContentType", func(t *testing.T) {
 		assert := assert.New(t)
 
 		src := Request{Version: "2.0-x", MethodName: "test.Add", Parameters: jsonParameterize([]interface{}{float64(1), float64(2), float64(3)}), ID: float64(1)}
 		r := makerequest(src)
 		r.Header.Set("Content-Type", "invalid/mime")
-		result, err := parseRPCRequests(r)
+		result, _, err := parseRPCRequests(r, 0)
 		assert.Nil(result)
 		assert.Error(err)
 		assert.IsType(errorBadContentType{}, err)
@@ -93,9 +106,54 @@ func TestParseRPCRequests(t *testing.T) {
 		r, err := http.NewRequest("POST", "/", strings.NewReader("garbagejson"))
 		r.Header.Set("Content-Type", "application/json")
 		must(err)
-		result, err := parseRPCRequests(r)
+		result, _, err := parseRPCRequests(r, 0)
 		assert.Nil(result)
 		assert.Error(err)
 		assert.IsType(errorInvalidJson{}, err)
 	})
+
+	bodyCase := func(body string, expectedBatch bool, expectedMethods []string, expectedInvalid []bool) func(t *testing.T) {
+		return func(t *testing.T) {
+			result, batch, err := parseRPCBody(strings.NewReader(body), 0)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedBatch, batch)
+			var methods []string
+			var invalid []bool
+			for _, r := range result {
+				methods = append(methods, r.MethodName)
+				invalid = append(invalid, r.invalid != nil)
+			}
+			assert.Equal(t, expectedMethods, methods)
+			assert.Equal(t, expectedInvalid, invalid)
+		}
+	}
+
+	t.Run("Stream/Whitespace", bodyCase(" \n\t{\"method\":\"a\",\"id\":1}", false, []string{"a"}, []bool{false}))
+	t.Run("Stream/Batch", bodyCase(`[{"method":"a","id":1}, 5, {"method":"b"}, {"id":2}, {"method":"c","params":"x"}]`, true, []string{"a", "", "b", "", ""}, []bool{false, true, false, true, true}))
+	t.Run("Stream/BatchOfOne", bodyCase(`[{"method":"a","id":1}]`, true, []string{"a"}, []bool{false}))
+	t.Run("Stream/EmptyBatch", func(t *testing.T) {
+		result, _, err := parseRPCBody(strings.NewReader(` [ ] `), 0)
+		assert.Nil(t, result)
+		assert.Equal(t, errorEmptyBatch{}, err)
+	})
+
+	invalidCase := func(body string) func(t *testing.T) {
+		return func(t *testing.T) {
+			result, _, err := parseRPCBody(strings.NewReader(body), 0)
+			assert.Nil(t, result)
+			assert.IsType(t, errorInvalidJson{}, err)
+		}
+	}
+
+	t.Run("Stream/BatchTooLarge", func(t *testing.T) {
+		// the garbage after the limit is never read
+		result, batch, err := parseRPCBody(strings.NewReader(`[{"method":"a","id":1},{"method":"b","id":2},{"method":"c","id":3},garbage`), 2)
+		assert.Nil(t, result)
+		assert.False(t, batch)
+		assert.Equal(t, errorBatchTooLarge{2}, err)
+	})
+
+	t.Run("Stream/Empty", invalidCase(``))
+	t.Run("Stream/Truncated", invalidCase(`[{"method":"a","id":1}, {"method"`))
+	t.Run("Stream/Unclosed", invalidCase(`[{"method":"a","id":1}`))
 }
@@ -2,10 +2,21 @@ package gojsonrpc
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 )
 
+// resultVersion is the jsonrpc member sent on every result
+const resultVersion = "2.0-x"
+
+// requestVersion is the jsonrpc member sent on requests and notifications from the server
+const requestVersion = "2.0"
+
 func (r Request) getNamespaceFunction() (string, string) {
 	v := strings.SplitN(r.MethodName, ".", 2)
 	if len(v) == 0 {
@@ -17,45 +28,201 @@ func (r Request) getNamespaceFunction() (string, string) {
 	}
 }
 
-func (h *Handler) asyncProcessRequest(c context.Context, req *Request, wg *sync.WaitGroup, results chan Result) {
-	defer wg.Done()
-	method, ok := h.cachedMethods[req.MethodName]
-	if !ok {
-		results <- Result{
-			ID: req.ID,
-			Error: &Error{
-				Code:    -32601,
-				Message: "method not found on server",
-			},
-			Version: "2.0-x",
+// processRequest runs a single request; notifications are run but have no result
+func (h *Handler) processRequest(c context.Context, req *Request) *Result {
+	if req.invalid != nil {
+		return &Result{
+			ID:      nil,
+			Error:   req.invalid,
+			Version: resultVersion,
 		}
-		return
 	}
 
-	result, err := method.Call(c, req.Parameters)
+	c = withCallInfo(c, req)
+	c, span := h.tracer.StartSpan(c, SpanCall)
+	defer span.Finish()
+	span.SetTag(TagMethod, req.MethodName)
+	if !req.Notification {
+		span.SetTag(TagID, req.ID)
+	}
+
+	var result interface{}
+	var err *Error
+	start := time.Now()
+	if h.acquireCall() {
+		slot := &callSlot{h: h, holders: 1}
+		result, err = h.invoke(withCallSlot(c, slot), req)
+		slot.release()
+	} else {
+		err = &Error{Code: CodeServerBusy, Message: "server busy; too many calls in flight"}
+	}
+	h.observeCall(req, err, time.Since(start))
+	if err != nil {
+		span.SetTag(TagErrorCode, err.Code)
+	}
+
+	if req.Notification {
+		discardResult(result)
+		return nil
+	}
+	if err != nil {
+		discardResult(result)
+		result = nil
+	}
 
-	results <- Result{
+	return &Result{
 		ID:      req.ID,
 		Result:  result,
 		Error:   err,
-		Version: "2.0-x",
+		Version: resultVersion,
+	}
+}
+
+// observeCall reports a finished call to the metrics collector
+func (h *Handler) observeCall(req *Request, err *Error, duration time.Duration) {
+	method := req.MethodName
+	if _, ok := h.lookupMethod(method); !ok {
+		method = unknownMethod
 	}
+	code := 0
+	if err != nil {
+		code = err.Code
+	}
+	h.metrics.ObserveCall(method, code, duration)
+}
+
+// invoke checks the method's requirements, then runs the request through the interceptors and then the method, so an
+// interceptor that short-circuits cannot skip the check; a panic anywhere along the way is reported to the panic
+// handler and answered with an internal error
+func (h *Handler) invoke(c context.Context, req *Request) (result interface{}, err *Error) {
+	defer h.recoverPanic(c, req, &result, &err)
+
+	authorized := req.MethodName
+	if method, ok := h.lookupMethod(authorized); ok {
+		if denied := h.authorize(c, req, method); denied != nil {
+			return nil, denied
+		}
+	}
+
+	return chainInterceptors(h.interceptors, func(c context.Context, req *Request) (interface{}, *Error) {
+		return h.callMethod(c, req, authorized)
+	})(c, req)
 }
 
+// recoverPanic turns a panic into an internal error; it must be deferred directly
+func (h *Handler) recoverPanic(c context.Context, req *Request, result *interface{}, err **Error) {
+	if recovered := recover(); recovered != nil {
+		stack := debug.Stack()
+		if h.panicHandler != nil {
+			h.panicHandler(c, req, recovered, stack)
+		} else {
+			log.Printf("panic calling %s: %v\n%s", req.MethodName, recovered, stack)
+		}
+		*result, *err = nil, &Error{Code: CodeInternalError, Message: "internal error"}
+	}
+}
+
+// callMethod looks up the method and calls it with the request params; authorized is the method whose requirements
+// were already checked, and any other method an interceptor switched the request to is checked here
+func (h *Handler) callMethod(c context.Context, req *Request, authorized string) (interface{}, *Error) {
+	method, ok := h.lookupMethod(req.MethodName)
+	if !ok {
+		return nil, &Error{
+			Code:    CodeMethodNotFound,
+			Message: "method not found on server",
+		}
+	}
+
+	if req.MethodName != authorized {
+		if err := h.authorize(c, req, method); err != nil {
+			return nil, err
+		}
+	}
+
+	return h.callWithDeadline(c, req, method, func(c context.Context) (interface{}, *Error) {
+		if req.NamedParameters != nil {
+			return method.CallNamed(c, req.NamedParameters)
+		}
+		return method.Call(c, req.Parameters)
+	})
+}
+
+// marshalResults marshals the results of a body or message; each result is marshaled on its own, so one that cannot
+// be marshaled only fails its own call
+func marshalResults(results []Result, batch bool) json.RawMessage {
+	encoded := make([]json.RawMessage, len(results))
+	for i, result := range results {
+		encoded[i] = marshalResult(result)
+	}
+	if !batch {
+		return encoded[0]
+	}
+	b, _ := json.Marshal(encoded)
+	return b
+}
+
+// marshalResult marshals a result, answering with an internal error if it cannot be marshaled
+func marshalResult(result Result) json.RawMessage {
+	b, err := marshalMessage(result)
+	if err == nil {
+		return b
+	}
+	log.Printf("cannot marshal result of %v: %v", result.ID, err)
+	b, _ = json.Marshal(Result{
+		ID:      result.ID,
+		Error:   &Error{Code: CodeInternalError, Message: "internal error"},
+		Version: resultVersion,
+	})
+	return b
+}
+
+// marshalMessage marshals msg, returning a MarshalJSON that panics as an error
+func marshalMessage(msg interface{}) (b json.RawMessage, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			b, err = nil, fmt.Errorf("panic marshaling message: %v", recovered)
+		}
+	}()
+	return json.Marshal(msg)
+}
+
+// processRequests runs every request, in parallel up to the batch parallelism, and returns the results in request order
 func (h *Handler) processRequests(c context.Context, requests []Request) ([]Result, error) {
-	wg := sync.WaitGroup{}
-	rchan := make(chan Result)
-	wg.Add(len(requests))
-	for i := range requests {
-		go h.asyncProcessRequest(c, &requests[i], &wg, rchan)
+	h.metrics.ObserveBatch(len(requests))
+	slots := make([]*Result, len(requests))
+	workers := h.batchParallelism
+	if workers <= 0 || workers > len(requests) {
+		workers = len(requests)
 	}
-	go func() {
+
+	if workers == 1 {
+		for i := range requests {
+			slots[i] = h.processRequest(c, &requests[i])
+		}
+	} else {
+		indexes := make(chan int)
+		wg := sync.WaitGroup{}
+		wg.Add(workers)
+		for w := 0; w < workers; w++ {
+			go func() {
+				defer wg.Done()
+				for i := range indexes {
+					slots[i] = h.processRequest(c, &requests[i])
+				}
+			}()
+		}
+		for i := range requests {
+			indexes <- i
+		}
+		close(indexes)
 		wg.Wait()
-		close(rchan)
-	}()
+	}
+
 	var results []Result
-	for r := range rchan {
-		results = append(results, r)
+	for _, r := range slots {
+		if r != nil {
+			results = append(results, *r)
+		}
 	}
 
 	return results, nil
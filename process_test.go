@@ -1,8 +1,11 @@
 package gojsonrpc
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNamespaceFunction(t *testing.T) {
@@ -17,3 +20,83 @@ func TestNamespaceFunction(t *testing.T) {
 	t.Run("test", nfs("test", "test", ""))
 	t.Run("test.Add.Deep", nfs("test", "test", ""))
 }
+
+type TestOrderNamespace struct {
+	mu    sync.Mutex
+	order []int
+}
+
+func (t *TestOrderNamespace) Sleep(ms int) int {
+	time.Sleep(time.Duration(ms) * time.Millisecond)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.order = append(t.order, ms)
+	return ms
+}
+
+func TestProcessRequestsOrder(t *testing.T) {
+	requests := func() []Request {
+		var requests []Request
+		for i, ms := range []int{30, 10, 20, 0} {
+			requests = append(requests, Request{MethodName: "test.Sleep", Parameters: jsonParameterize([]interface{}{ms}), ID: i})
+		}
+		return requests
+	}
+
+	run := func(sequential bool, expectedOrder []int) func(t *testing.T) {
+		return func(t *testing.T) {
+			n := &TestOrderNamespace{}
+			var options []Option
+			if sequential {
+				options = append(options, WithSequentialBatches())
+			}
+			h := New(DefaultNext(), options...)
+			must(h.AddNamespace("test", n))
+			results, err := h.processRequests(context.Background(), requests())
+			assert.NoError(t, err)
+			if assert.Len(t, results, 4) {
+				for i, ms := range []int{30, 10, 20, 0} {
+					assert.Equal(t, i, results[i].ID)
+					assert.Equal(t, ms, results[i].Result)
+				}
+			}
+			if expectedOrder != nil {
+				assert.Equal(t, expectedOrder, n.order)
+			}
+		}
+	}
+
+	t.Run("Parallel", run(false, nil))
+	t.Run("Sequential", run(true, []int{30, 10, 20, 0}))
+}
+
+type TestPanicNamespace struct{}
+
+func (t *TestPanicNamespace) Panic() int {
+	panic("something broke")
+}
+
+func (t *TestPanicNamespace) Echo(v int) int {
+	return v
+}
+
+func TestProcessRequestsPanic(t *testing.T) {
+	var recovered []interface{}
+	h := New(DefaultNext(), WithPanicHandler(func(c context.Context, req *Request, v interface{}, stack []byte) {
+		assert.Equal(t, "test.Panic", req.MethodName)
+		assert.NotEmpty(t, stack)
+		recovered = append(recovered, v)
+	}), WithSequentialBatches())
+	must(h.AddNamespace("test", &TestPanicNamespace{}))
+
+	results, err := h.processRequests(context.Background(), []Request{
+		{MethodName: "test.Panic", ID: 1},
+		{MethodName: "test.Echo", Parameters: jsonParameterize([]interface{}{5}), ID: 2},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, &Error{Code: CodeInternalError, Message: "internal error"}, results[0].Error)
+		assert.Equal(t, 5, results[1].Result)
+	}
+	assert.Equal(t, []interface{}{"something broke"}, recovered)
+}
@@ -0,0 +1,149 @@
+package gojsonrpc
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// methodRegistry holds the registered methods; it is safe to change while requests are being served
+type methodRegistry struct {
+	mu         sync.RWMutex
+	methods    map[string]*parameterizedMethod
+	namespaces map[string][]string // method names registered by each namespace
+}
+
+func newMethodRegistry() *methodRegistry {
+	return &methodRegistry{
+		methods:    make(map[string]*parameterizedMethod),
+		namespaces: make(map[string][]string),
+	}
+}
+
+func (r *methodRegistry) get(name string) (*parameterizedMethod, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.methods[name]
+	return m, ok
+}
+
+// set registers a standalone method; if it replaces a namespace method, it no longer belongs to that namespace
+func (r *methodRegistry) set(name string, m *parameterizedMethod) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unlinkLocked(name)
+	r.methods[name] = m
+}
+
+func (r *methodRegistry) remove(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.methods[name]
+	delete(r.methods, name)
+	r.unlinkLocked(name)
+	return ok
+}
+
+// unlinkLocked removes the method name from whichever namespace registered it
+func (r *methodRegistry) unlinkLocked(name string) {
+	for namespace, names := range r.namespaces {
+		for i, n := range names {
+			if n == name {
+				r.namespaces[namespace] = append(names[:i:i], names[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// setNamespace replaces every method of the namespace in one step, so callers never see a partially registered namespace
+func (r *methodRegistry) setNamespace(namespace string, methods map[string]*parameterizedMethod) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removeNamespaceLocked(namespace)
+	names := make([]string, 0, len(methods))
+	for name, m := range methods {
+		r.methods[name] = m
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	r.namespaces[namespace] = names
+}
+
+func (r *methodRegistry) removeNamespace(namespace string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.removeNamespaceLocked(namespace)
+}
+
+func (r *methodRegistry) removeNamespaceLocked(namespace string) bool {
+	names, ok := r.namespaces[namespace]
+	for _, name := range names {
+		delete(r.methods, name)
+	}
+	delete(r.namespaces, namespace)
+	return ok
+}
+
+func (r *methodRegistry) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.methods))
+	for name := range r.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *methodRegistry) namespaceNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.namespaces))
+	for name := range r.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupMethod finds a registered method, falling back to the built in methods
+func (h *Handler) lookupMethod(name string) (*parameterizedMethod, bool) {
+	if m, ok := h.methods.get(name); ok {
+		return m, true
+	}
+	m, ok := h.builtins[name]
+	return m, ok
+}
+
+// addBuiltinMethods registers the methods every handler answers; they use the "rpc." prefix the specification reserves,
+// and are not listed by Methods
+func (h *Handler) addBuiltinMethods() {
+	unsubscribe, err := h.newMethod(reflect.ValueOf(func(ws *Conn, id string) bool {
+		return ws.unsubscribe(id)
+	}))
+	if err != nil {
+		panic(err)
+	}
+	if err := unsubscribe.setParameterNames([]string{"subscription"}); err != nil {
+		panic(err)
+	}
+	h.builtins = map[string]*parameterizedMethod{
+		UnsubscribeMethod: unsubscribe,
+	}
+}
+
+// RemoveNamespace removes every method registered by AddNamespace under name, returning false if there was none
+func (h *Handler) RemoveNamespace(name string) bool {
+	return h.methods.removeNamespace(name)
+}
+
+// Methods lists the names of every registered method, sorted
+func (h *Handler) Methods() []string {
+	return h.methods.names()
+}
+
+// Namespaces lists the names of every registered namespace, sorted
+func (h *Handler) Namespaces() []string {
+	return h.methods.namespaceNames()
+}
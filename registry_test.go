@@ -0,0 +1,79 @@
+package gojsonrpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type TestFeatureNamespace struct{}
+
+func (t *TestFeatureNamespace) On() bool {
+	return true
+}
+
+func (t *TestFeatureNamespace) Off() bool {
+	return false
+}
+
+type TestFeatureV2Namespace struct{}
+
+func (t *TestFeatureV2Namespace) On() string {
+	return "v2"
+}
+
+func TestMethodRegistry(t *testing.T) {
+	t.Run("Listing", func(t *testing.T) {
+		h := New(DefaultNext())
+		must(h.AddNamespace("feature", &TestFeatureNamespace{}))
+		must(h.AddMethod("ping", func() string { return "pong" }))
+		assert.Equal(t, []string{"feature.Off", "feature.On", "ping"}, h.Methods())
+		assert.Equal(t, []string{"feature"}, h.Namespaces())
+	})
+
+	t.Run("RemoveNamespace", func(t *testing.T) {
+		h := New(DefaultNext())
+		must(h.AddNamespace("feature", &TestFeatureNamespace{}))
+		must(h.AddMethod("ping", func() string { return "pong" }))
+		assert.True(t, h.RemoveNamespace("feature"))
+		assert.False(t, h.RemoveNamespace("feature"))
+		assert.Equal(t, []string{"ping"}, h.Methods())
+		assert.Empty(t, h.Namespaces())
+	})
+
+	t.Run("ReplaceNamespace", func(t *testing.T) {
+		h := New(DefaultNext())
+		must(h.AddNamespace("feature", &TestFeatureNamespace{}))
+		must(h.AddNamespace("feature", &TestFeatureV2Namespace{}))
+		assert.Equal(t, []string{"feature.On"}, h.Methods())
+	})
+
+	t.Run("ReplaceNamespaceMethod", func(t *testing.T) {
+		h := New(DefaultNext())
+		must(h.AddNamespace("feature", &TestFeatureNamespace{}))
+		must(h.AddMethod("feature.On", func() string { return "override" }))
+		h.RemoveNamespace("feature")
+		assert.Equal(t, []string{"feature.On"}, h.Methods())
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		h := New(DefaultNext())
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				must(h.AddNamespace(fmt.Sprintf("feature%d", i%3), &TestFeatureNamespace{}))
+				h.RemoveNamespace(fmt.Sprintf("feature%d", (i+1)%3))
+			}(i)
+			go func() {
+				defer wg.Done()
+				_, err := h.processRequests(context.Background(), []Request{{MethodName: "feature0.On", ID: 1}})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+	})
+}
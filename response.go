@@ -0,0 +1,109 @@
+package gojsonrpc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Response lets methods change the HTTP response their call is sent in; it is shared by every call in a batch, and
+// does nothing outside of HTTP, where it is nil
+type Response struct {
+	mu      sync.Mutex
+	header  http.Header
+	cookies []*http.Cookie
+	status  int
+}
+
+type responseKey struct{}
+
+func newResponse() *Response {
+	return &Response{header: make(http.Header)}
+}
+
+func withResponse(c context.Context, r *Response) context.Context {
+	return context.WithValue(c, responseKey{}, r)
+}
+
+// ResponseFromContext returns the HTTP response the call will be sent in
+func ResponseFromContext(c context.Context) (*Response, bool) {
+	r, ok := c.Value(responseKey{}).(*Response)
+	return r, ok
+}
+
+// SetHeader sets a header on the HTTP response, replacing any values already set
+func (r *Response) SetHeader(key, value string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.header.Set(key, value)
+}
+
+// AddHeader adds a header value to the HTTP response
+func (r *Response) AddHeader(key, value string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.header.Add(key, value)
+}
+
+// SetCookie adds a Set-Cookie header to the HTTP response
+func (r *Response) SetCookie(cookie *http.Cookie) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cookies = append(r.cookies, cookie)
+}
+
+// SetStatus sets the HTTP status; it is only used when the request was not a batch
+func (r *Response) SetStatus(status int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// WithErrorStatus sends status as the HTTP status when a request that is not a batch fails with code, unless the
+// method sets its own status
+func WithErrorStatus(code int, status int) Option {
+	return func(h *Handler) {
+		if h.errorStatus == nil {
+			h.errorStatus = make(map[int]int)
+		}
+		h.errorStatus[code] = status
+	}
+}
+
+// writeHeader copies what the methods set onto w and writes the status; defaultStatus is used unless something else
+// applies, and batches always use it
+func (h *Handler) writeHeader(w http.ResponseWriter, r *Response, batch bool, results []Result, defaultStatus int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// headers the methods set replace the handler's own, such as Content-Type
+	for key, values := range r.header {
+		w.Header()[key] = values
+	}
+	for _, cookie := range r.cookies {
+		http.SetCookie(w, cookie)
+	}
+
+	status := defaultStatus
+	if !batch {
+		if r.status != 0 {
+			status = r.status
+		} else if len(results) == 1 && results[0].Error != nil {
+			if mapped, ok := h.errorStatus[results[0].Error.Code]; ok {
+				status = mapped
+			}
+		}
+	}
+	w.WriteHeader(status)
+}
@@ -0,0 +1,81 @@
+package gojsonrpc
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type TestSessionNamespace struct{}
+
+func (t *TestSessionNamespace) Login(c context.Context, user string) string {
+	r, _ := ResponseFromContext(c)
+	r.SetCookie(&http.Cookie{Name: "session", Value: user})
+	r.AddHeader("X-User", user)
+	return user
+}
+
+func (t *TestSessionNamespace) Typed(r *Response) bool {
+	r.SetHeader("Content-Type", "application/json-rpc")
+	return true
+}
+
+func (t *TestSessionNamespace) Create(r *Response) bool {
+	r.SetStatus(http.StatusCreated)
+	return true
+}
+
+func (t *TestSessionNamespace) Fail() error {
+	return &Error{Code: CodeUnauthorized, Message: "unauthorized"}
+}
+
+func TestResponse(t *testing.T) {
+	h := New(DefaultNext(), WithErrorStatus(CodeUnauthorized, http.StatusUnauthorized))
+	must(h.AddNamespace("session", &TestSessionNamespace{}))
+
+	t.Run("Cookie", func(t *testing.T) {
+		w := serve(h, `{"jsonrpc":"2.0","method":"session.Login","params":["alice"],"id":1}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "session=alice", w.Header().Get("Set-Cookie"))
+		assert.Equal(t, "alice", w.Header().Get("X-User"))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("ReplaceHeader", func(t *testing.T) {
+		w := serve(h, `{"jsonrpc":"2.0","method":"session.Typed","id":1}`)
+		assert.Equal(t, []string{"application/json-rpc"}, w.Header()["Content-Type"])
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		w := serve(h, `{"jsonrpc":"2.0","method":"session.Create","id":1}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("ErrorStatus", func(t *testing.T) {
+		w := serve(h, `{"jsonrpc":"2.0","method":"session.Fail","id":1}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.JSONEq(t, `{"jsonrpc":"2.0-x","id":1,"error":{"code":-32001,"message":"unauthorized"}}`, w.Body.String())
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		w := serve(h, `[{"jsonrpc":"2.0","method":"session.Create","id":1},{"jsonrpc":"2.0","method":"session.Fail","id":2},{"jsonrpc":"2.0","method":"session.Login","params":["bob"],"id":3}]`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "session=bob", w.Header().Get("Set-Cookie"))
+	})
+
+	t.Run("Notification", func(t *testing.T) {
+		w := serve(h, `{"jsonrpc":"2.0","method":"session.Login","params":["carol"]}`)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "session=carol", w.Header().Get("Set-Cookie"))
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		r, ok := ResponseFromContext(context.Background())
+		assert.False(t, ok)
+		assert.NotPanics(t, func() {
+			r.SetHeader("X", "y")
+			r.SetStatus(http.StatusTeapot)
+		})
+	})
+}
@@ -0,0 +1,145 @@
+package gojsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+const (
+	// SubscriptionMethod is the method of the notifications sent for a subscription; their params are
+	// {"subscription": id, "result": value}
+	SubscriptionMethod = "rpc.subscription"
+	// UnsubscribeMethod is the built in method a client calls with a subscription id to end it
+	UnsubscribeMethod = "rpc.unsubscribe"
+)
+
+// ErrSubscriptionDone is returned when notifying a subscription that was ended by either side or whose connection closed
+var ErrSubscriptionDone = errors.New("subscription done")
+
+// Subscription streams notifications to the connection that created it; the method that creates it must return it as
+// its result, which sends the client its id, marshaled as a string
+type Subscription struct {
+	id   string
+	conn *Conn
+
+	// ready is closed once the id has been sent, so no notification reaches the client before it
+	ready     chan struct{}
+	readyOnce sync.Once
+	done      chan struct{}
+	doneOnce  sync.Once
+}
+
+// subscriptionResult is the params of a subscription notification
+type subscriptionResult struct {
+	Subscription string      `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
+
+// Subscribe creates a subscription on the connection; it ends when the client calls rpc.unsubscribe, when Unsubscribe
+// is called, or when the connection closes
+func (ws *Conn) Subscribe() (*Subscription, error) {
+	if ws == nil {
+		return nil, ErrNotWebsocket
+	}
+	s := &Subscription{
+		id:    randomHexID(16),
+		conn:  ws,
+		ready: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	ws.subscriptionsMu.Lock()
+	defer ws.subscriptionsMu.Unlock()
+	if ws.c.Err() != nil {
+		return nil, ErrConnClosed
+	}
+	ws.subscriptions[s.id] = s
+	return s, nil
+}
+
+// unsubscribe ends the subscription with the id, returning false if the connection has none
+func (ws *Conn) unsubscribe(id string) bool {
+	if ws == nil {
+		return false
+	}
+	ws.subscriptionsMu.Lock()
+	s, ok := ws.subscriptions[id]
+	delete(ws.subscriptions, id)
+	ws.subscriptionsMu.Unlock()
+	if ok {
+		s.end()
+	}
+	return ok
+}
+
+// endSubscriptions ends every subscription when the connection closes
+func (ws *Conn) endSubscriptions() {
+	ws.subscriptionsMu.Lock()
+	subscriptions := ws.subscriptions
+	ws.subscriptions = make(map[string]*Subscription)
+	ws.subscriptionsMu.Unlock()
+	for _, s := range subscriptions {
+		s.end()
+	}
+}
+
+// ID is the id the client uses to tell notifications apart and to unsubscribe
+func (s *Subscription) ID() string {
+	return s.id
+}
+
+// MarshalJSON writes the id
+func (s *Subscription) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.id)
+}
+
+// activate lets notifications be sent, once the result with the id has been queued ahead of them
+func (s *Subscription) activate() {
+	s.readyOnce.Do(func() { close(s.ready) })
+}
+
+// Notify sends result to the client; it waits until the subscription id has been sent, and returns
+// ErrSubscriptionDone once the subscription has ended, or an error if result cannot be marshaled
+func (s *Subscription) Notify(result interface{}) error {
+	select {
+	case <-s.ready:
+	case <-s.done:
+		return ErrSubscriptionDone
+	}
+	select {
+	case <-s.done:
+		return ErrSubscriptionDone
+	default:
+	}
+	err := s.conn.send(notification{
+		Version: requestVersion,
+		Method:  SubscriptionMethod,
+		Params:  subscriptionResult{s.id, result},
+	})
+	if err == ErrConnClosed {
+		return ErrSubscriptionDone
+	}
+	return err
+}
+
+// Done is closed when the subscription ends
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Unsubscribe ends the subscription from the server
+func (s *Subscription) Unsubscribe() {
+	s.conn.unsubscribe(s.id)
+}
+
+// discardResult ends a subscription that a method returned but that is never sent to the client, so its notifications
+// do not wait for it forever
+func discardResult(result interface{}) {
+	if s, ok := result.(*Subscription); ok && s != nil {
+		s.Unsubscribe()
+	}
+}
+
+func (s *Subscription) end() {
+	s.doneOnce.Do(func() { close(s.done) })
+}
@@ -0,0 +1,124 @@
+package gojsonrpc
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type TestCounterNamespace struct {
+	ended chan string
+}
+
+func (t *TestCounterNamespace) Count(ws *Conn, from int) (*Subscription, error) {
+	s, err := ws.Subscribe()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for i := from; s.Notify(i) == nil; i++ {
+		}
+		t.ended <- s.ID()
+	}()
+	return s, nil
+}
+
+func (t *TestCounterNamespace) Broken(ws *Conn) (*Subscription, error) {
+	s, err := t.Count(ws, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s, &Error{Code: CodeServerError, Message: "broken"}
+}
+
+func TestSubscription(t *testing.T) {
+	n := &TestCounterNamespace{ended: make(chan string, 1)}
+	h := New(DefaultNext())
+	must(h.AddNamespace("counter", n))
+
+	receive := func(t *testing.T, conn *websocket.Conn, v interface{}) {
+		must(json.Unmarshal([]byte(readMessage(t, conn)), v))
+	}
+
+	subscribe := func(t *testing.T, conn *websocket.Conn) string {
+		must(conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"counter.Count","params":[5],"id":1}`)))
+		var result Result
+		receive(t, conn, &result)
+		assert.Nil(t, result.Error)
+		id, _ := result.Result.(string)
+		assert.Len(t, id, 32)
+		return id
+	}
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		conn, done := dial(t, h)
+		defer done()
+		id := subscribe(t, conn)
+
+		var notification struct {
+			Method string
+			Params struct {
+				Subscription string
+				Result       int
+			}
+		}
+		receive(t, conn, &notification)
+		assert.Equal(t, SubscriptionMethod, notification.Method)
+		assert.Equal(t, id, notification.Params.Subscription)
+		assert.Equal(t, 5, notification.Params.Result)
+
+		must(conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"rpc.unsubscribe","params":["`+id+`"],"id":2}`)))
+		for {
+			var result Result
+			receive(t, conn, &result)
+			if result.ID != nil {
+				assert.Equal(t, float64(2), result.ID)
+				assert.Equal(t, true, result.Result)
+				break
+			}
+		}
+		assert.Equal(t, id, <-n.ended)
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		conn, done := dial(t, h)
+		id := subscribe(t, conn)
+		done()
+		select {
+		case ended := <-n.ended:
+			assert.Equal(t, id, ended)
+		case <-time.After(time.Second):
+			t.Fatal("subscription did not end")
+		}
+	})
+
+	// a subscription whose id never reaches the client ends straight away, instead of waiting for the connection to close
+	dropped := func(frame string) func(t *testing.T) {
+		return func(t *testing.T) {
+			conn, done := dial(t, h)
+			defer done()
+			must(conn.WriteMessage(websocket.TextMessage, []byte(frame)))
+			select {
+			case <-n.ended:
+			case <-time.After(time.Second):
+				t.Fatal("subscription did not end")
+			}
+		}
+	}
+
+	t.Run("Notification", dropped(`{"jsonrpc":"2.0","method":"counter.Count","params":[5]}`))
+	t.Run("Error", dropped(`{"jsonrpc":"2.0","method":"counter.Broken","id":1}`))
+
+	t.Run("HTTP", func(t *testing.T) {
+		w := serve(h, `{"jsonrpc":"2.0","method":"counter.Count","params":[5],"id":1}`)
+		var result Result
+		must(json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, ErrNotWebsocket.Error(), result.Error.Message)
+
+		w = serve(h, `{"jsonrpc":"2.0","method":"rpc.unsubscribe","params":{"subscription":"missing"},"id":1}`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0-x","id":1,"result":false}`, w.Body.String())
+		assert.NotContains(t, h.Methods(), UnsubscribeMethod)
+	})
+}
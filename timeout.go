@@ -0,0 +1,75 @@
+package gojsonrpc
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TimeoutHeader lets HTTP clients limit how long their request may take, in milliseconds
+const TimeoutHeader = "X-JSONRPC-Timeout"
+
+// WithTimeout limits how long every method may run, unless the method sets its own Timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(h *Handler) {
+		h.timeout = timeout
+	}
+}
+
+// withClientTimeout applies the timeout sent in TimeoutHeader to the request's context; invalid values are ignored
+func withClientTimeout(r *http.Request) (context.Context, context.CancelFunc, bool) {
+	ms, err := strconv.ParseInt(r.Header.Get(TimeoutHeader), 10, 64)
+	if err != nil || ms <= 0 {
+		return nil, nil, false
+	}
+	c, cancel := context.WithTimeout(r.Context(), time.Duration(ms)*time.Millisecond)
+	return c, cancel, true
+}
+
+// callOutcome is what a call running in its own goroutine hands back
+type callOutcome struct {
+	result interface{}
+	err    *Error
+}
+
+// callWithDeadline applies the method's timeout, and if the context has a deadline, stops waiting for the call once it
+// passes and answers with a timeout error; the call itself keeps running until it notices the context is done
+func (h *Handler) callWithDeadline(c context.Context, req *Request, method *parameterizedMethod, call func(c context.Context) (interface{}, *Error)) (interface{}, *Error) {
+	timeout := method.timeout
+	if timeout == 0 {
+		timeout = h.timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		c, cancel = context.WithTimeout(c, timeout)
+		defer cancel()
+	}
+	if _, ok := c.Deadline(); !ok {
+		return call(c)
+	}
+
+	done := make(chan callOutcome, 1)
+	// the call holds on to its concurrency slot until it returns, even after it is abandoned
+	slot := callSlotFromContext(c)
+	slot.hold()
+	go func() {
+		defer slot.release()
+		var o callOutcome
+		defer func() { done <- o }()
+		defer h.recoverPanic(c, req, &o.result, &o.err)
+		o.result, o.err = call(c)
+	}()
+
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-c.Done():
+		// nobody will see what the abandoned call returns
+		go func() { discardResult((<-done).result) }()
+		if c.Err() == context.DeadlineExceeded {
+			return nil, &Error{Code: CodeTimeout, Message: "timeout"}
+		}
+		return nil, &Error{Code: CodeServerError, Message: c.Err().Error()}
+	}
+}
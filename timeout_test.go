@@ -0,0 +1,72 @@
+package gojsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeouts(t *testing.T) {
+	slow := func(c context.Context, ms int) bool {
+		select {
+		case <-time.After(time.Duration(ms) * time.Millisecond):
+			return true
+		case <-c.Done():
+			return false
+		}
+	}
+
+	callCase := func(options []Option, methodOptions []MethodOption, ms int, expectedError *Error) func(t *testing.T) {
+		return func(t *testing.T) {
+			h := New(DefaultNext(), options...)
+			must(h.AddMethod("slow", slow, methodOptions...))
+			results, err := h.processRequests(context.Background(), []Request{{MethodName: "slow", Parameters: jsonParameterize([]interface{}{ms}), ID: 1}})
+			assert.NoError(t, err)
+			if assert.Len(t, results, 1) {
+				assert.Equal(t, expectedError, results[0].Error)
+				if expectedError == nil {
+					assert.Equal(t, true, results[0].Result)
+				}
+			}
+		}
+	}
+
+	timeout := &Error{Code: CodeTimeout, Message: "timeout"}
+
+	t.Run("None", callCase(nil, nil, 10, nil))
+	t.Run("Default/Within", callCase([]Option{WithTimeout(time.Second)}, nil, 10, nil))
+	t.Run("Default/Exceeded", callCase([]Option{WithTimeout(10 * time.Millisecond)}, nil, 1000, timeout))
+	t.Run("Method/Overrides", callCase([]Option{WithTimeout(10 * time.Millisecond)}, []MethodOption{Timeout(time.Second)}, 50, nil))
+	t.Run("Method/Exceeded", callCase(nil, []MethodOption{Timeout(10 * time.Millisecond)}, 1000, timeout))
+
+	t.Run("Ignored", func(t *testing.T) {
+		h := New(DefaultNext(), WithTimeout(10*time.Millisecond))
+		must(h.AddMethod("stuck", func() bool {
+			time.Sleep(100 * time.Millisecond)
+			return true
+		}))
+		start := time.Now()
+		results, _ := h.processRequests(context.Background(), []Request{{MethodName: "stuck", ID: 1}})
+		assert.Equal(t, timeout, results[0].Error)
+		assert.True(t, time.Since(start) < 100*time.Millisecond)
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		h := New(DefaultNext())
+		must(h.AddMethod("slow", slow))
+		r, err := http.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","method":"slow","params":[1000],"id":1}`))
+		must(err)
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set(TimeoutHeader, "10")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		var result Result
+		must(json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, timeout, result.Error)
+	})
+}
@@ -0,0 +1,178 @@
+package gojsonrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Tracer starts spans around the work of a handler: one per HTTP request or WebSocket message, and a child per call
+type Tracer interface {
+	// StartSpan starts a span that is a child of the span in the context, if any, and returns a context carrying it
+	StartSpan(c context.Context, name string) (context.Context, Span)
+}
+
+// Span is a unit of traced work
+type Span interface {
+	SetTag(key string, value interface{})
+	Finish()
+}
+
+// span names and tags used by the handler
+const (
+	SpanHTTP      = "jsonrpc.http"
+	SpanWebsocket = "jsonrpc.websocket"
+	SpanCall      = "jsonrpc.call"
+
+	TagMethod    = "rpc.method"
+	TagID        = "rpc.id"
+	TagErrorCode = "rpc.error_code"
+)
+
+// WithTracer traces requests and calls with tracer
+func WithTracer(tracer Tracer) Option {
+	return func(h *Handler) {
+		h.tracer = tracer
+	}
+}
+
+type noTracer struct{}
+
+func (noTracer) StartSpan(c context.Context, name string) (context.Context, Span) {
+	return c, noSpan{}
+}
+
+type noSpan struct{}
+
+func (noSpan) SetTag(key string, value interface{}) {}
+func (noSpan) Finish()                              {}
+
+// TraceParent is a W3C trace context, as sent by callers in the traceparent header
+type TraceParent struct {
+	TraceID  string
+	ParentID string
+	Flags    string
+}
+
+type traceParentKey struct{}
+
+// ParseTraceParent parses a traceparent header value; only version 00 is understood
+func ParseTraceParent(header string) (TraceParent, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 || parts[0] != "00" || !isHex(parts[1], 32) || !isHex(parts[2], 16) || !isHex(parts[3], 2) {
+		return TraceParent{}, false
+	}
+	// all zero ids are invalid
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return TraceParent{}, false
+	}
+	return TraceParent{TraceID: parts[1], ParentID: parts[2], Flags: parts[3]}, true
+}
+
+// isHex checks s is lowercase hex of the given length
+func isHex(s string, length int) bool {
+	if len(s) != length || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// TraceParentFromContext returns the trace context the caller sent, for tracers to continue the caller's trace
+func TraceParentFromContext(c context.Context) (TraceParent, bool) {
+	tp, ok := c.Value(traceParentKey{}).(TraceParent)
+	return tp, ok
+}
+
+// withTraceParent extracts the traceparent header into the context
+func withTraceParent(c context.Context, r *http.Request) context.Context {
+	if tp, ok := ParseTraceParent(r.Header.Get("traceparent")); ok {
+		return context.WithValue(c, traceParentKey{}, tp)
+	}
+	return c
+}
+
+// RecordingTracer keeps every span in memory, for tests
+type RecordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+// RecordedSpan is a span kept by a RecordingTracer
+type RecordedSpan struct {
+	Name     string
+	TraceID  string
+	SpanID   string
+	ParentID string
+	Tags     map[string]interface{}
+	Finished bool
+}
+
+type recordingSpan struct {
+	tracer *RecordingTracer
+	data   RecordedSpan
+}
+
+type recordingSpanKey struct{}
+
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+func (t *RecordingTracer) StartSpan(c context.Context, name string) (context.Context, Span) {
+	s := &recordingSpan{tracer: t, data: RecordedSpan{
+		Name:   name,
+		SpanID: randomHexID(8),
+		Tags:   make(map[string]interface{}),
+	}}
+	if parent, ok := c.Value(recordingSpanKey{}).(*recordingSpan); ok {
+		s.data.TraceID, s.data.ParentID = parent.data.TraceID, parent.data.SpanID
+	} else if tp, ok := TraceParentFromContext(c); ok {
+		s.data.TraceID, s.data.ParentID = tp.TraceID, tp.ParentID
+	} else {
+		s.data.TraceID = randomHexID(16)
+	}
+
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(c, recordingSpanKey{}, s), s
+}
+
+// Spans returns a copy of every span started so far, in the order they were started
+func (t *RecordingTracer) Spans() []RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans := make([]RecordedSpan, len(t.spans))
+	for i, s := range t.spans {
+		spans[i] = s.data
+		spans[i].Tags = make(map[string]interface{}, len(s.data.Tags))
+		for k, v := range s.data.Tags {
+			spans[i].Tags[k] = v
+		}
+	}
+	return spans
+}
+
+func (s *recordingSpan) SetTag(key string, value interface{}) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.data.Tags[key] = value
+}
+
+func (s *recordingSpan) Finish() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.data.Finished = true
+}
+
+func randomHexID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
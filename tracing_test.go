@@ -0,0 +1,58 @@
+package gojsonrpc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	parseCase := func(header string, expected TraceParent, expectedOk bool) func(t *testing.T) {
+		return func(t *testing.T) {
+			actual, ok := ParseTraceParent(header)
+			assert.Equal(t, expectedOk, ok)
+			assert.Equal(t, expected, actual)
+		}
+	}
+
+	t.Run("Valid", parseCase("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", TraceParent{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01"}, true))
+	t.Run("Empty", parseCase("", TraceParent{}, false))
+	t.Run("Version", parseCase("ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", TraceParent{}, false))
+	t.Run("Uppercase", parseCase("00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", TraceParent{}, false))
+	t.Run("ZeroTrace", parseCase("00-00000000000000000000000000000000-00f067aa0ba902b7-01", TraceParent{}, false))
+	t.Run("ShortParent", parseCase("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01", TraceParent{}, false))
+}
+
+func TestTracing(t *testing.T) {
+	tracer := NewRecordingTracer()
+	h := New(DefaultNext(), WithTracer(tracer), WithSequentialBatches())
+	must(h.AddMethod("ping", func() string { return "pong" }))
+
+	r, err := http.NewRequest("POST", "/", strings.NewReader(`[{"jsonrpc":"2.0","method":"ping","id":1},{"jsonrpc":"2.0","method":"nope","id":"b"}]`))
+	must(err)
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := tracer.Spans()
+	if assert.Len(t, spans, 3) {
+		root, ping, nope := spans[0], spans[1], spans[2]
+		assert.Equal(t, SpanHTTP, root.Name)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", root.TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", root.ParentID)
+
+		for _, s := range []RecordedSpan{root, ping, nope} {
+			assert.True(t, s.Finished)
+			assert.Equal(t, root.TraceID, s.TraceID)
+		}
+
+		assert.Equal(t, SpanCall, ping.Name)
+		assert.Equal(t, root.SpanID, ping.ParentID)
+		assert.Equal(t, map[string]interface{}{TagMethod: "ping", TagID: float64(1)}, ping.Tags)
+
+		assert.Equal(t, root.SpanID, nope.ParentID)
+		assert.Equal(t, map[string]interface{}{TagMethod: "nope", TagID: "b", TagErrorCode: CodeMethodNotFound}, nope.Tags)
+	}
+}
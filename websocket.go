@@ -0,0 +1,334 @@
+package gojsonrpc
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Conn is a single WebSocket connection, reachable from the context of calls made over it with ConnFromContext;
+// messages are handled concurrently and everything sent is written by a single writer, which stops when the connection
+// is done
+type Conn struct {
+	h       *Handler
+	conn    *websocket.Conn
+	request *http.Request
+
+	// c is cancelled when the connection is closed or breaks; calls made over the connection use it
+	c      context.Context
+	cancel context.CancelFunc
+	// out is the queue of marshaled messages waiting for the writer
+	out chan json.RawMessage
+	// handlers tracks the goroutines handling messages, so the connection is only closed once they are done
+	handlers sync.WaitGroup
+
+	subscriptionsMu sync.Mutex
+	subscriptions   map[string]*Subscription
+
+	// topics the connection joined; guarded by the hub
+	topics map[string]struct{}
+
+	// calls made to the client that are waiting for a response, by id; lastID is guarded by pendingMu
+	pendingMu sync.Mutex
+	pending   map[string]chan callResponse
+	lastID    uint64
+}
+
+var (
+	// ErrNotWebsocket is returned when a call that needs a WebSocket connection came in over HTTP
+	ErrNotWebsocket = errors.New("not a WebSocket connection")
+	// ErrConnClosed is returned when using a WebSocket connection that has closed
+	ErrConnClosed = errors.New("connection closed")
+)
+
+// defaultQueueSize is how many messages can wait to be written to each connection unless WithQueueSize is used
+const defaultQueueSize = 16
+
+// WithQueueSize sets how many messages can wait to be written to each WebSocket connection; when a connection's queue
+// is full, published notifications are dropped for it, while results wait for room
+func WithQueueSize(n int) Option {
+	return func(h *Handler) {
+		h.queueSize = n
+	}
+}
+
+// WithCheckOrigin decides which origins may open a WebSocket connection; by default only the request's own host may
+func WithCheckOrigin(check func(r *http.Request) bool) Option {
+	return func(h *Handler) {
+		h.upgrader.CheckOrigin = check
+	}
+}
+
+// WithSubprotocols sets the WebSocket subprotocols the handler supports, in order of preference, for example "jsonrpc"
+func WithSubprotocols(protocols ...string) Option {
+	return func(h *Handler) {
+		h.upgrader.Subprotocols = protocols
+	}
+}
+
+// WithMaxMessageSize closes WebSocket connections that send a message larger than n bytes
+func WithMaxMessageSize(n int64) Option {
+	return func(h *Handler) {
+		h.maxMessageSize = n
+	}
+}
+
+// WithKeepalive pings WebSocket connections every interval, and closes them if nothing, message or pong, is received
+// within timeout; writes that take longer than timeout also close the connection. The interval must be shorter than
+// the timeout, so a healthy client has time to answer a ping
+func WithKeepalive(interval time.Duration, timeout time.Duration) Option {
+	if interval <= 0 || timeout <= interval {
+		panic(fmt.Sprintf("WithKeepalive expects a positive interval shorter than the timeout; got %v and %v", interval, timeout))
+	}
+	return func(h *Handler) {
+		h.pingInterval = interval
+		h.idleTimeout = timeout
+	}
+}
+
+// WithCompression negotiates permessage-deflate with WebSocket clients that support it, compressing at the flate level
+func WithCompression(level int) Option {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		panic(fmt.Sprintf("WithCompression expects a flate compression level; got %d", level))
+	}
+	return func(h *Handler) {
+		h.upgrader.EnableCompression = true
+		h.compressionLevel = level
+	}
+}
+
+// WithBufferSizes sets the size of the read and write buffers of each WebSocket connection; the default is 1024 each
+func WithBufferSizes(read int, write int) Option {
+	return func(h *Handler) {
+		h.upgrader.ReadBufferSize = read
+		h.upgrader.WriteBufferSize = write
+	}
+}
+
+type connKey struct{}
+
+func withConn(c context.Context, conn *Conn) context.Context {
+	return context.WithValue(c, connKey{}, conn)
+}
+
+// ConnFromContext returns the WebSocket connection the call came in on
+func ConnFromContext(c context.Context) (*Conn, bool) {
+	conn, ok := c.Value(connKey{}).(*Conn)
+	return conn, ok
+}
+
+func (h *Handler) ServeWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already answered the request
+		log.Println(err)
+		return
+	}
+	h.metrics.AddInFlight(TransportWebsocket, 1)
+	defer h.metrics.AddInFlight(TransportWebsocket, -1)
+
+	c, cancel := context.WithCancel(r.Context())
+	ws := &Conn{
+		h:       h,
+		conn:    conn,
+		request: r,
+		c:       c,
+		cancel:  cancel,
+		out:     make(chan json.RawMessage, h.queueSize),
+
+		subscriptions: make(map[string]*Subscription),
+		topics:        make(map[string]struct{}),
+		pending:       make(map[string]chan callResponse),
+	}
+	ws.configure()
+	ws.serve()
+}
+
+// configure applies the handler's WebSocket options to the connection
+func (ws *Conn) configure() {
+	h := ws.h
+	if h.maxMessageSize > 0 {
+		ws.conn.SetReadLimit(h.maxMessageSize)
+	}
+	if h.upgrader.EnableCompression {
+		ws.conn.EnableWriteCompression(true)
+		ws.conn.SetCompressionLevel(h.compressionLevel)
+	}
+	if h.idleTimeout > 0 {
+		ws.extendReadDeadline()
+		ws.conn.SetPongHandler(func(string) error {
+			ws.extendReadDeadline()
+			return nil
+		})
+	}
+}
+
+// extendReadDeadline gives the client another idle timeout to send something
+func (ws *Conn) extendReadDeadline() {
+	ws.conn.SetReadDeadline(time.Now().Add(ws.h.idleTimeout))
+}
+
+// ping sends pings until the connection is done
+func (ws *Conn) ping() {
+	ticker := time.NewTicker(ws.h.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// a ping that cannot be written before the next one is due means the connection is dead
+			if err := ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(ws.h.pingInterval)); err != nil {
+				log.Println(err)
+				ws.cancel()
+				ws.conn.Close()
+				return
+			}
+		case <-ws.c.Done():
+			return
+		}
+	}
+}
+
+// serve reads messages until the connection closes, then waits for the handlers before closing it
+func (ws *Conn) serve() {
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		ws.write()
+	}()
+	if ws.h.pingInterval > 0 {
+		go ws.ping()
+	}
+
+	ws.read()
+
+	ws.cancel()
+	ws.endSubscriptions()
+	ws.h.hub.leaveAll(ws)
+	ws.handlers.Wait()
+	<-writerDone
+	ws.conn.Close()
+}
+
+func (ws *Conn) read() {
+	for {
+		_, p, err := ws.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("error: %v, user-agent: %v", err, ws.request.Header.Get("User-Agent"))
+			}
+			return
+		}
+		if ws.h.idleTimeout > 0 {
+			ws.extendReadDeadline()
+		}
+		if ws.c.Err() != nil {
+			// the writer broke; nothing more can be answered
+			return
+		}
+		if ws.routeResponses(p) {
+			continue
+		}
+		ws.handlers.Add(1)
+		go func(p []byte) {
+			defer ws.handlers.Done()
+			ws.handleMessage(p)
+		}(p)
+	}
+}
+
+// write sends messages until the connection is done; a failed write ends the connection
+func (ws *Conn) write() {
+	for {
+		select {
+		case msg := <-ws.out:
+			if ws.h.idleTimeout > 0 {
+				ws.conn.SetWriteDeadline(time.Now().Add(ws.h.idleTimeout))
+			}
+			if err := ws.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				log.Println(err)
+				ws.cancel()
+				// unblock the reader
+				ws.conn.Close()
+				return
+			}
+		case <-ws.c.Done():
+			return
+		}
+	}
+}
+
+// send marshals msg and queues it for the writer, returning ErrConnClosed if the connection is done
+func (ws *Conn) send(msg interface{}) error {
+	b, err := marshalMessage(msg)
+	if err != nil {
+		return err
+	}
+	return ws.sendBytes(b)
+}
+
+// sendBytes queues a marshaled message for the writer, returning ErrConnClosed if the connection is done
+func (ws *Conn) sendBytes(b json.RawMessage) error {
+	select {
+	case ws.out <- b:
+		return nil
+	case <-ws.c.Done():
+		return ErrConnClosed
+	}
+}
+
+// trySend queues msg for the writer without waiting, returning false if the queue is full or the connection is done
+func (ws *Conn) trySend(msg json.RawMessage) bool {
+	if ws.c.Err() != nil {
+		return false
+	}
+	select {
+	case ws.out <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// handleMessage answers a single frame, following the same rules as an HTTP body; since there is no HandlerNext to
+// fall back on, bad frames are always answered with spec errors
+func (ws *Conn) handleMessage(p []byte) {
+	c, span := ws.h.tracer.StartSpan(withTraceParent(withConn(ws.c, ws), ws.request), SpanWebsocket)
+	defer span.Finish()
+
+	requests, batch, err := parseRPCBody(bytes.NewReader(p), ws.h.maxBatchSize)
+	if _, ok := err.(errorEmptyBatch); ok {
+		requests, batch = []Request{emptyBatch()}, false
+	} else if tooLarge, ok := err.(errorBatchTooLarge); ok {
+		requests, batch = []Request{batchTooLarge(tooLarge.limit)}, false
+	} else if err != nil {
+		requests, batch = []Request{{invalid: &Error{Code: CodeParseError, Message: "parse error"}}}, false
+	}
+
+	results, err := ws.h.processRequests(withMessageInfo(c, TransportWebsocket, ws.request, batch), requests)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if len(results) == 0 {
+		return
+	}
+
+	if ws.sendBytes(marshalResults(results, batch)) != nil {
+		return
+	}
+
+	// subscriptions can send notifications once the client has their id
+	for _, result := range results {
+		if s, ok := result.Result.(*Subscription); ok {
+			s.activate()
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package gojsonrpc
+
+import (
+	"compress/flate"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dial starts a server for h and opens a WebSocket connection to it
+func dial(t *testing.T, h *Handler) (*websocket.Conn, func()) {
+	server := httptest.NewServer(h)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	must(err)
+	return conn, func() {
+		conn.Close()
+		server.Close()
+	}
+}
+
+// readMessage reads the next frame, failing if nothing arrives in time
+func readMessage(t *testing.T, conn *websocket.Conn) string {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, p, err := conn.ReadMessage()
+	must(err)
+	return string(p)
+}
+
+func TestWebsocket(t *testing.T) {
+	h := New(DefaultNext(), WithMaxBatchSize(2))
+	must(h.AddNamespace("test", &TestHandlerNamespace{}))
+	conn, done := dial(t, h)
+	defer done()
+
+	exchange := func(frame string, expected string) func(t *testing.T) {
+		return func(t *testing.T) {
+			must(conn.WriteMessage(websocket.TextMessage, []byte(frame)))
+			assert.JSONEq(t, expected, readMessage(t, conn))
+		}
+	}
+
+	t.Run("Single", exchange(`{"jsonrpc":"2.0","method":"test.Add","params":[1,2],"id":1}`, `{"jsonrpc":"2.0-x","id":1,"result":3}`))
+	t.Run("Batch", exchange(`[{"jsonrpc":"2.0","method":"test.Add","params":[1,2],"id":1},{"jsonrpc":"2.0","method":"test.Add","params":[3,4]}]`, `[{"jsonrpc":"2.0-x","id":1,"result":3}]`))
+	t.Run("ParseError", exchange(`{"jsonrpc":`, `{"jsonrpc":"2.0-x","id":null,"error":{"code":-32700,"message":"parse error"}}`))
+	t.Run("InvalidRequest", exchange(`{"jsonrpc":"2.0","id":1}`, `{"jsonrpc":"2.0-x","id":null,"error":{"code":-32600,"message":"invalid request; missing method"}}`))
+	t.Run("BatchTooLarge", func(t *testing.T) {
+		must(conn.WriteMessage(websocket.TextMessage, []byte(`[1,2,3]`)))
+		var result Result
+		must(json.Unmarshal([]byte(readMessage(t, conn)), &result))
+		assert.Equal(t, CodeInvalidRequest, result.Error.Code)
+	})
+
+	t.Run("Notification", func(t *testing.T) {
+		// the notification has no answer, so the next frame is the answer to the call after it
+		must(conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"test.Add","params":[1,2]}`)))
+		exchange(`{"jsonrpc":"2.0","method":"test.Add","params":[2,2],"id":2}`, `{"jsonrpc":"2.0-x","id":2,"result":4}`)(t)
+	})
+}
+
+type TestPanicMarshaler struct{}
+
+func (TestPanicMarshaler) MarshalJSON() ([]byte, error) {
+	panic("boom")
+}
+
+func TestWebsocketMarshalPanic(t *testing.T) {
+	h := New(DefaultNext())
+	must(h.AddMethod("bad", func() TestPanicMarshaler { return TestPanicMarshaler{} }))
+	must(h.AddMethod("ping", func() string { return "pong" }))
+	conn, done := dial(t, h)
+	defer done()
+
+	must(conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"bad","id":1}`)))
+	assert.JSONEq(t, `{"jsonrpc":"2.0-x","id":1,"error":{"code":-32603,"message":"internal error"}}`, readMessage(t, conn))
+
+	// in a batch only the call that cannot be marshaled fails, and the connection keeps working
+	must(conn.WriteMessage(websocket.TextMessage, []byte(`[{"jsonrpc":"2.0","method":"bad","id":1},{"jsonrpc":"2.0","method":"ping","id":2}]`)))
+	assert.JSONEq(t, `[{"jsonrpc":"2.0-x","id":1,"error":{"code":-32603,"message":"internal error"}},{"jsonrpc":"2.0-x","id":2,"result":"pong"}]`, readMessage(t, conn))
+}
+
+func TestWebsocketClose(t *testing.T) {
+	release := make(chan struct{})
+	h := New(DefaultNext())
+	must(h.AddMethod("wait", func() int {
+		<-release
+		return 1
+	}))
+	returned := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(returned)
+		h.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	must(err)
+
+	// a call still running when the client goes away must not crash, and the connection is closed once it finishes
+	must(conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"wait","id":1}`)))
+	time.Sleep(10 * time.Millisecond)
+	conn.Close()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("connection was not closed")
+	}
+}
+
+func TestWebsocketOptions(t *testing.T) {
+	// serveUntilClosed starts a server for h, closing returned once the connection is served
+	serveUntilClosed := func(h *Handler) (*httptest.Server, chan struct{}) {
+		returned := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r)
+			if websocket.IsWebSocketUpgrade(r) {
+				close(returned)
+			}
+		}))
+		return server, returned
+	}
+	url := func(server *httptest.Server) string {
+		return "ws" + strings.TrimPrefix(server.URL, "http")
+	}
+	waitClosed := func(t *testing.T, returned chan struct{}) {
+		select {
+		case <-returned:
+		case <-time.After(time.Second):
+			t.Fatal("connection was not closed")
+		}
+	}
+
+	t.Run("CheckOrigin", func(t *testing.T) {
+		h := New(DefaultNext(), WithCheckOrigin(func(r *http.Request) bool {
+			return r.Header.Get("Origin") == "https://example.com"
+		}))
+		server := httptest.NewServer(h)
+		defer server.Close()
+
+		_, resp, err := websocket.DefaultDialer.Dial(url(server), http.Header{"Origin": {"https://evil.example"}})
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		conn, _, err := websocket.DefaultDialer.Dial(url(server), http.Header{"Origin": {"https://example.com"}})
+		must(err)
+		conn.Close()
+	})
+
+	t.Run("Subprotocols", func(t *testing.T) {
+		server := httptest.NewServer(New(DefaultNext(), WithSubprotocols("jsonrpc")))
+		defer server.Close()
+		dialer := websocket.Dialer{Subprotocols: []string{"other", "jsonrpc"}}
+		conn, _, err := dialer.Dial(url(server), nil)
+		must(err)
+		defer conn.Close()
+		assert.Equal(t, "jsonrpc", conn.Subprotocol())
+	})
+
+	t.Run("MaxMessageSize", func(t *testing.T) {
+		server, returned := serveUntilClosed(New(DefaultNext(), WithMaxMessageSize(16)))
+		defer server.Close()
+		conn, _, err := websocket.DefaultDialer.Dial(url(server), nil)
+		must(err)
+		defer conn.Close()
+
+		must(conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"too.long","id":1}`)))
+		_, _, err = conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), "%v", err)
+		waitClosed(t, returned)
+	})
+
+	t.Run("Keepalive", func(t *testing.T) {
+		server, returned := serveUntilClosed(New(DefaultNext(), WithKeepalive(5*time.Millisecond, 50*time.Millisecond)))
+		defer server.Close()
+
+		// a client that reads answers pings, so it stays connected past the timeout
+		conn, _, err := websocket.DefaultDialer.Dial(url(server), nil)
+		must(err)
+		pings := make(chan struct{}, 100)
+		conn.SetPingHandler(func(data string) error {
+			pings <- struct{}{}
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		go conn.ReadMessage()
+		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-returned:
+			t.Fatal("live connection was closed")
+		default:
+		}
+		assert.NotEmpty(t, pings)
+		conn.Close()
+		waitClosed(t, returned)
+
+		assert.Panics(t, func() { WithKeepalive(time.Second, time.Second) })
+		assert.Panics(t, func() { WithKeepalive(0, time.Second) })
+	})
+
+	t.Run("IdleTimeout", func(t *testing.T) {
+		server, returned := serveUntilClosed(New(DefaultNext(), WithKeepalive(5*time.Millisecond, 20*time.Millisecond)))
+		defer server.Close()
+
+		// a client that never reads never answers pings
+		conn, _, err := websocket.DefaultDialer.Dial(url(server), nil)
+		must(err)
+		defer conn.Close()
+		waitClosed(t, returned)
+	})
+
+	t.Run("Compression", func(t *testing.T) {
+		h := New(DefaultNext(), WithCompression(flate.BestSpeed), WithBufferSizes(4096, 4096))
+		must(h.AddNamespace("test", &TestHandlerNamespace{}))
+		server := httptest.NewServer(h)
+		defer server.Close()
+		dialer := websocket.Dialer{EnableCompression: true}
+		conn, resp, err := dialer.Dial(url(server), nil)
+		must(err)
+		defer conn.Close()
+		assert.Contains(t, resp.Header.Get("Sec-Websocket-Extensions"), "permessage-deflate")
+
+		must(conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"test.Add","params":[1,2],"id":1}`)))
+		assert.JSONEq(t, `{"jsonrpc":"2.0-x","id":1,"result":3}`, readMessage(t, conn))
+
+		assert.Panics(t, func() { WithCompression(10) })
+	})
+}